@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var shellNames = []string{"bash", "zsh", "fish", "powershell"}
+
+// completionEntry lists the candidates offered after the words in path
+// have been typed.
+type completionEntry struct {
+	path       string
+	candidates []completionCandidate
+}
+
+type completionCandidate struct {
+	value   string
+	summary string
+}
+
+// completionEntries flattens the command tree into one entry per
+// command that accepts further words.
+func completionEntries(cmd *command, path string) []completionEntry {
+	result := []completionEntry{}
+	entry := completionEntry{path: path}
+	for _, sub := range cmd.subcommands {
+		entry.candidates = append(entry.candidates, completionCandidate{value: sub.name, summary: sub.summary})
+	}
+	if cmd.values != nil {
+		for _, value := range cmd.values() {
+			entry.candidates = append(entry.candidates, completionCandidate{value: value})
+		}
+	}
+	if len(entry.candidates) > 0 {
+		result = append(result, entry)
+	}
+	for _, sub := range cmd.subcommands {
+		result = append(result, completionEntries(sub, path+" "+sub.name)...)
+	}
+	return result
+}
+
+func completionCommand(args []string) int {
+	if len(args) != 1 {
+		usage(os.Stderr)
+		return 2
+	}
+	if err := writeCompletion(os.Stdout, args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "completion: %s\n", err)
+		return 2
+	}
+	return 0
+}
+
+// writeCompletion writes the completion script for shell to w.
+func writeCompletion(w io.Writer, shell string) error {
+	entries := completionEntries(root, root.name)
+	switch shell {
+	case "bash":
+		writeBashCompletion(w, entries)
+	case "zsh":
+		writeZshCompletion(w, entries)
+	case "fish":
+		writeFishCompletion(w, entries)
+	case "powershell":
+		writePowerShellCompletion(w, entries)
+	default:
+		return fmt.Errorf("unsupported shell '%s' (expected one of %s)", shell, strings.Join(shellNames, ", "))
+	}
+	return nil
+}
+
+func candidateValues(entry completionEntry) string {
+	values := []string{}
+	for _, c := range entry.candidates {
+		values = append(values, c.value)
+	}
+	return strings.Join(values, " ")
+}
+
+func writeBashCompletion(w io.Writer, entries []completionEntry) {
+	fmt.Fprintf(w, "# bash completion for htc\n")
+	fmt.Fprintf(w, "_htc() {\n")
+	fmt.Fprintf(w, "\tlocal cur cmdpath i\n")
+	fmt.Fprintf(w, "\tcur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	fmt.Fprintf(w, "\tcmdpath=\"htc\"\n")
+	fmt.Fprintf(w, "\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+	fmt.Fprintf(w, "\t\tcmdpath=\"$cmdpath ${COMP_WORDS[i]}\"\n")
+	fmt.Fprintf(w, "\tdone\n")
+	fmt.Fprintf(w, "\tcase \"$cmdpath\" in\n")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "\t\"%s\")\n", entry.path)
+		fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", candidateValues(entry))
+		fmt.Fprintf(w, "\t\t;;\n")
+	}
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -F _htc htc\n")
+}
+
+func writeZshCompletion(w io.Writer, entries []completionEntry) {
+	fmt.Fprintf(w, "#compdef htc\n")
+	fmt.Fprintf(w, "_htc() {\n")
+	fmt.Fprintf(w, "\tlocal cmdpath=\"htc\" i\n")
+	fmt.Fprintf(w, "\tlocal -a candidates\n")
+	fmt.Fprintf(w, "\tfor ((i = 2; i < CURRENT; i++)); do\n")
+	fmt.Fprintf(w, "\t\tcmdpath=\"$cmdpath ${words[i]}\"\n")
+	fmt.Fprintf(w, "\tdone\n")
+	fmt.Fprintf(w, "\tcase \"$cmdpath\" in\n")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "\t\"%s\")\n", entry.path)
+		fmt.Fprintf(w, "\t\tcandidates=(\n")
+		for _, c := range entry.candidates {
+			if c.summary != "" {
+				fmt.Fprintf(w, "\t\t\t'%s:%s'\n", c.value, c.summary)
+			} else {
+				fmt.Fprintf(w, "\t\t\t'%s'\n", c.value)
+			}
+		}
+		fmt.Fprintf(w, "\t\t)\n")
+		fmt.Fprintf(w, "\t\t;;\n")
+	}
+	fmt.Fprintf(w, "\tesac\n")
+	fmt.Fprintf(w, "\t_describe 'htc' candidates\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "compdef _htc htc\n")
+}
+
+func writeFishCompletion(w io.Writer, entries []completionEntry) {
+	fmt.Fprintf(w, "# fish completion for htc\n")
+	fmt.Fprintf(w, "function __htc_path\n")
+	fmt.Fprintf(w, "\tset -l words (commandline -opc)\n")
+	fmt.Fprintf(w, "\tset words[1] htc\n")
+	fmt.Fprintf(w, "\tstring join ' ' $words\n")
+	fmt.Fprintf(w, "end\n")
+	fmt.Fprintf(w, "complete -c htc -f\n")
+	for _, entry := range entries {
+		for _, c := range entry.candidates {
+			fmt.Fprintf(w, "complete -c htc -n 'test (__htc_path) = \"%s\"' -a '%s'", entry.path, c.value)
+			if c.summary != "" {
+				fmt.Fprintf(w, " -d '%s'", c.summary)
+			}
+			fmt.Fprintf(w, "\n")
+		}
+	}
+}
+
+func writePowerShellCompletion(w io.Writer, entries []completionEntry) {
+	fmt.Fprintf(w, "# PowerShell completion for htc\n")
+	fmt.Fprintf(w, "Register-ArgumentCompleter -Native -CommandName htc -ScriptBlock {\n")
+	fmt.Fprintf(w, "\tparam($wordToComplete, $commandAst, $cursorPosition)\n")
+	fmt.Fprintf(w, "\t$words = @($commandAst.CommandElements | ForEach-Object { $_.ToString() })\n")
+	fmt.Fprintf(w, "\tif ($wordToComplete -ne '') {\n")
+	fmt.Fprintf(w, "\t\t$words = @($words | Select-Object -First ($words.Count - 1))\n")
+	fmt.Fprintf(w, "\t}\n")
+	fmt.Fprintf(w, "\t$words[0] = 'htc'\n")
+	fmt.Fprintf(w, "\t$candidates = switch ($words -join ' ') {\n")
+	for _, entry := range entries {
+		quoted := []string{}
+		for _, c := range entry.candidates {
+			quoted = append(quoted, "'"+c.value+"'")
+		}
+		fmt.Fprintf(w, "\t\t'%s' { @(%s) }\n", entry.path, strings.Join(quoted, ", "))
+	}
+	fmt.Fprintf(w, "\t}\n")
+	fmt.Fprintf(w, "\t$candidates | Where-Object { $_ -like \"$wordToComplete*\" } | ForEach-Object {\n")
+	fmt.Fprintf(w, "\t\t[System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)\n")
+	fmt.Fprintf(w, "\t}\n")
+	fmt.Fprintf(w, "}\n")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompletionEntries(t *testing.T) {
+	entries := completionEntries(root, root.name)
+	found := map[string]string{}
+	for _, entry := range entries {
+		found[entry.path] = candidateValues(entry)
+	}
+
+	expected := map[string]string{
+		"htc":               "examples completion stats",
+		"htc examples":      "list show",
+		"htc examples show": "factorial fizzbuzz gcd hello sieve",
+		"htc completion":    "bash zsh fish powershell",
+		"htc stats":         "enable disable show",
+	}
+	if len(found) != len(expected) {
+		t.Errorf("expected %d entries, got %d", len(expected), len(found))
+	}
+	for path, values := range expected {
+		if found[path] != values {
+			t.Errorf("%s: expected '%s', got '%s'", path, values, found[path])
+		}
+	}
+}
+
+func TestWriteCompletion(t *testing.T) {
+	for _, shell := range shellNames {
+		var sb strings.Builder
+		if err := writeCompletion(&sb, shell); err != nil {
+			t.Errorf("%s: unexpected error %s", shell, err)
+			continue
+		}
+		if !strings.Contains(sb.String(), "fizzbuzz") {
+			t.Errorf("%s: script does not offer example names", shell)
+		}
+	}
+
+	var sb strings.Builder
+	if err := writeCompletion(&sb, "tcsh"); err == nil {
+		t.Error("expected error for unsupported shell")
+	}
+}
@@ -0,0 +1,59 @@
+// Package examples embeds a small corpus of htc programs. The corpus is
+// used by the "htc examples" command and as fixtures by tests.
+package examples
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed src/*.c
+var corpus embed.FS
+
+// Example is a single program from the embedded corpus.
+type Example struct {
+	Name   string
+	Source string
+}
+
+// ErrNotFound is returned by Get when no example has the given name.
+var ErrNotFound = errors.New("example not found")
+
+// Names returns the names of all embedded examples in sorted order.
+func Names() []string {
+	entries, err := fs.ReadDir(corpus, "src")
+	if err != nil {
+		return nil
+	}
+	result := []string{}
+	for _, entry := range entries {
+		result = append(result, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Get returns the example with the given name.
+func Get(name string) (Example, error) {
+	data, err := corpus.ReadFile(path.Join("src", name+".c"))
+	if err != nil {
+		return Example{}, ErrNotFound
+	}
+	return Example{Name: name, Source: string(data)}, nil
+}
+
+// All returns every embedded example in name order.
+func All() []Example {
+	result := []Example{}
+	for _, name := range Names() {
+		ex, err := Get(name)
+		if err == nil {
+			result = append(result, ex)
+		}
+	}
+	return result
+}
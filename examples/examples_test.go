@@ -0,0 +1,40 @@
+package examples
+
+import (
+	"testing"
+
+	"github.com/hculpan/htc/lexer"
+)
+
+func TestNames(t *testing.T) {
+	names := Names()
+	expected := []string{"factorial", "fizzbuzz", "gcd", "hello", "sieve"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected %d examples, got %d", len(expected), len(names))
+	}
+	for idx, name := range expected {
+		if names[idx] != name {
+			t.Errorf("expected %s, got %s", name, names[idx])
+		}
+	}
+}
+
+func TestGetUnknown(t *testing.T) {
+	if _, err := Get("nope"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCorpusLexes(t *testing.T) {
+	for _, ex := range All() {
+		l := lexer.NewLexer(ex.Source)
+		for _, tok := range l.Tokens() {
+			if tok.Type == lexer.ILLEGAL {
+				t.Errorf("%s: illegal token '%s' at line %d", ex.Name, tok.Literal, tok.Line)
+			}
+		}
+		if l.HasErrors() {
+			t.Errorf("%s: unexpected lexer errors: %v", ex.Name, l.Errors())
+		}
+	}
+}
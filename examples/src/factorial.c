@@ -0,0 +1,13 @@
+int factorial(int n) {
+	if (n == 0)
+		return 1;
+	else
+		return n * factorial(n - 1);
+}
+
+int main() {
+	int i;
+	for (i = 0; i <= 5; i++)
+		printf("Factorial of %d is %d\n", i, factorial(i));
+	return 0;
+}
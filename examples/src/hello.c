@@ -0,0 +1,5 @@
+// The smallest complete program.
+int main() {
+	printf("Hello, world!\n");
+	return 0;
+}
@@ -0,0 +1,18 @@
+/* Sieve of Eratosthenes: print the primes below 100. */
+int main() {
+	int composite[100];
+	int i;
+	int j;
+
+	for (i = 0; i < 100; i++)
+		composite[i] = 0;
+
+	for (i = 2; i < 100; i++) {
+		if (composite[i] == 0) {
+			printf("%d\n", i);
+			for (j = i * i; j < 100; j += i)
+				composite[j] = 1;
+		}
+	}
+	return 0;
+}
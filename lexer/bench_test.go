@@ -0,0 +1,78 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hculpan/htc/examples"
+)
+
+// benchmarkInput returns the example corpus repeated to about 1MB.
+func benchmarkInput() string {
+	var sb strings.Builder
+	for sb.Len() < 1<<20 {
+		for _, ex := range examples.All() {
+			sb.WriteString(ex.Source)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+func BenchmarkTokens(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewLexer(input).Tokens()
+	}
+}
+
+func BenchmarkNextToken(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l := NewLexer(input)
+		for l.NextToken().Type != EOF {
+		}
+	}
+}
+
+func BenchmarkTokensFromReader(b *testing.B) {
+	input := benchmarkInput()
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewLexerFromReader(strings.NewReader(input)).Tokens()
+	}
+}
+
+func BenchmarkUpdate(b *testing.B) {
+	input := benchmarkInput()
+	l := NewLexer(input)
+	l.Update(Range{}, "")
+	middle := len(input) / 2
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// alternately insert and remove one space
+		if i%2 == 0 {
+			l.Update(Range{Start: middle, End: middle}, " ")
+		} else {
+			l.Update(Range{Start: middle, End: middle + 1}, "")
+		}
+	}
+}
+
+func TestNextTokenDoesNotAllocate(t *testing.T) {
+	input := strings.Repeat("int main() { x = \"text\" + 0x1F; // note\n}\n", 100)
+	l := NewLexer(input)
+	allocs := testing.AllocsPerRun(1000, func() {
+		l.NextToken()
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations per token, got %v", allocs)
+	}
+}
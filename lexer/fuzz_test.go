@@ -0,0 +1,90 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hculpan/htc/examples"
+)
+
+// FuzzLexer checks that any input lexes to a well-formed token stream
+// ending in EOF, and that the streaming lexer agrees with the string one.
+// Run it with go test -fuzz=FuzzLexer ./lexer.
+func FuzzLexer(f *testing.F) {
+	for _, ex := range examples.All() {
+		f.Add(ex.Source)
+	}
+	for _, seed := range []string{"", "//", "/*", "\"", "\"\\", "0x", "\x00", "a\x00b", "\xff\xfe", "\xef\xbb\xbf", "\r\r\n", "é\xc3"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := NewLexer(input)
+		tokens := l.Tokens()
+		checkTokens(t, input, tokens)
+
+		streamed := NewLexerFromReader(strings.NewReader(input)).Tokens()
+		if len(streamed) != len(tokens) {
+			t.Fatalf("streaming lexer returned %d tokens, expected %d", len(streamed), len(tokens))
+		}
+		for idx, tok := range streamed {
+			if tok != tokens[idx] {
+				t.Fatalf("token %d: streaming lexer returned %+v, expected %+v", idx, tok, tokens[idx])
+			}
+		}
+	})
+}
+
+// checkTokens reports tokens that are not a well-formed lexing of input.
+func checkTokens(t *testing.T, input string, tokens []Token) {
+	t.Helper()
+	if len(tokens) == 0 || tokens[len(tokens)-1].Type != EOF {
+		t.Fatalf("expected tokens to end with EOF, got %+v", tokens)
+	}
+	offset, line := 0, 1
+	for idx, tok := range tokens {
+		if tok.Type == EOF && idx != len(tokens)-1 {
+			t.Fatalf("token %d: EOF before the end", idx)
+		}
+		if tok.StartOffset < offset || tok.EndOffset < tok.StartOffset || tok.EndOffset > len(input) {
+			t.Fatalf("token %d: span %d-%d out of order after %d", idx, tok.StartOffset, tok.EndOffset, offset)
+		}
+		if tok.Line < line || tok.EndLine < tok.Line || tok.Position < 1 {
+			t.Fatalf("token %d: position %d:%d-%d:%d out of order after line %d", idx, tok.Line, tok.Position, tok.EndLine, tok.EndColumn, line)
+		}
+		switch tok.Type {
+		case STRING, IDENT, EOF:
+			// decoded or normalized literals may differ from the source
+		default:
+			if tok.Literal != input[tok.StartOffset:tok.EndOffset] {
+				t.Fatalf("token %d: literal %q does not match source %q", idx, tok.Literal, input[tok.StartOffset:tok.EndOffset])
+			}
+		}
+		offset, line = tok.EndOffset, tok.EndLine
+	}
+}
+
+func TestLexerBinaryInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []ExpectedToken
+	}{
+		{"a // trailing", []ExpectedToken{{IDENT, "a"}, {COMMENT, "// trailing"}, {EOF, ""}}},
+		{"a\x00b", []ExpectedToken{{IDENT, "a"}, {ILLEGAL, "\x00"}, {IDENT, "b"}, {EOF, ""}}},
+		{"\xff+", []ExpectedToken{{ILLEGAL, "\xff"}, {PLUS, "+"}, {EOF, ""}}},
+	}
+	for _, tt := range tests {
+		l := NewLexer(tt.input)
+		tokens := l.Tokens()
+		checkTokens(t, tt.input, tokens)
+		if len(tokens) != len(tt.expected) {
+			t.Errorf("%q: expected %d tokens, got %+v", tt.input, len(tt.expected), tokens)
+			continue
+		}
+		for idx, tok := range tokens {
+			if tok.Type != tt.expected[idx].Type || tok.Literal != tt.expected[idx].Literal {
+				t.Errorf("%q: token %d: expected %+v, got %+v", tt.input, idx, tt.expected[idx], tok)
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package lexer
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// latinLookalikes maps Cyrillic and Greek letters to the Latin letters
+// they are easily mistaken for.
+var latinLookalikes = map[rune]rune{
+	'а': 'a', 'в': 'B', 'е': 'e', 'к': 'k', 'м': 'M', 'н': 'H', 'о': 'o',
+	'р': 'p', 'с': 'c', 'т': 'T', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j',
+	'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'А': 'A', 'В': 'B', 'Е': 'E',
+	'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T',
+	'Х': 'X', 'І': 'I', 'Ј': 'J', 'Ѕ': 'S',
+	'α': 'a', 'ο': 'o', 'ν': 'v', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'Α': 'A',
+	'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M',
+	'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+}
+
+// normalizeIdentifier returns ident in Unicode normalization form C, so
+// identifiers that look the same compare equal regardless of how the
+// editor composed them. It warns about identifiers containing letters
+// that imitate Latin ones.
+func (l *Lexer) normalizeIdentifier(ident string, line int, column int) string {
+	ascii := true
+	for i := 0; i < len(ident); i++ {
+		if ident[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return ident
+	}
+
+	if !norm.NFC.IsNormalString(ident) {
+		ident = norm.NFC.String(ident)
+	}
+	if r, latin, ok := confusable(ident); ok {
+		l.addWarningAt(line, column, fmt.Sprintf("identifier '%s' contains U+%04X, which looks like Latin '%c'", ident, r, latin))
+	}
+	return ident
+}
+
+// confusable reports the first lookalike letter in ident if the
+// identifier mixes it with Latin letters or consists only of lookalikes.
+func confusable(ident string) (rune, rune, bool) {
+	var first, latin rune
+	hasLatin, onlyLookalikes := false, true
+	for _, r := range ident {
+		if lookalike, ok := latinLookalikes[r]; ok {
+			if first == 0 {
+				first, latin = r, lookalike
+			}
+			continue
+		}
+		if unicode.Is(unicode.Latin, r) {
+			hasLatin = true
+		}
+		if unicode.IsLetter(r) {
+			onlyLookalikes = false
+		}
+	}
+	if first == 0 {
+		return 0, 0, false
+	}
+	return first, latin, hasLatin || onlyLookalikes
+}
@@ -0,0 +1,58 @@
+package lexer
+
+import (
+	"testing"
+)
+
+func TestIdentifiersWithDigits(t *testing.T) {
+	input := "foo2 a1b2 _x9"
+
+	expected := []ExpectedToken{
+		{Type: "IDENT", Literal: "foo2"},
+		{Type: "IDENT", Literal: "a1b2"},
+		{Type: "IDENT", Literal: "_x9"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, NewLexer(input), t)
+}
+
+func TestIdentifierNormalization(t *testing.T) {
+	// "e" followed by a combining acute accent composes to "é"
+	l := NewLexer("cafe\u0301 = caf\u00e9;")
+
+	expected := []ExpectedToken{
+		{Type: "IDENT", Literal: "caf\u00e9"},
+		{Type: "=", Literal: "="},
+		{Type: "IDENT", Literal: "caf\u00e9"},
+		{Type: ";", Literal: ";"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, l, t)
+	if len(l.Warnings()) != 0 {
+		t.Errorf("expected no warnings, got %v", l.Warnings())
+	}
+}
+
+func TestConfusableIdentifiers(t *testing.T) {
+	inputs := map[string]string{
+		"pаy = 1;":     "[1:1] identifier 'pаy' contains U+0430, which looks like Latin 'a'",
+		"x = ех;":      "[1:5] identifier 'ех' contains U+0435, which looks like Latin 'e'",
+		"int привет;":  "",
+		"int Straße;":  "",
+		"int αβγ = 0;": "",
+	}
+
+	for input, warning := range inputs {
+		l := NewLexer(input)
+		l.Tokens()
+		if warning == "" {
+			if len(l.Warnings()) != 0 {
+				t.Errorf("%s: expected no warnings, got %v", input, l.Warnings())
+			}
+		} else if len(l.Warnings()) != 1 {
+			t.Errorf("%s: expected 1 warning, got %v", input, l.Warnings())
+		} else if l.Warnings()[0].Error() != warning {
+			t.Errorf("%s: expected '%s', got '%s'", input, warning, l.Warnings()[0].Error())
+		}
+	}
+}
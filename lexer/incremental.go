@@ -0,0 +1,249 @@
+package lexer
+
+import "strings"
+
+// Range is the part of the input between two byte offsets. End is
+// exclusive.
+type Range struct {
+	Start int
+	End   int
+}
+
+// TokenEdit describes how an Update changed the tokens of the document:
+// Removed tokens from index Start on were replaced by Tokens. The tokens
+// after them are unchanged apart from their positions, which move with
+// the text after the edit.
+type TokenEdit struct {
+	Start   int
+	Removed int
+	Tokens  []Token
+}
+
+// document is the state Update keeps between edits.
+type document struct {
+	text    *rope
+	tokens  tokenStore
+	limited bool // lexing stopped at a limit, so the tokens end early
+}
+
+// Update replaces the text in edit with newText and reports which tokens
+// changed. Only the region around the edit is lexed again; tokens before
+// it are kept, and tokens after it are kept with their positions moved
+// as soon as lexing resynchronizes with the old token stream. The first
+// call lexes the whole input.
+//
+// The text and tokens are kept in chunks, so an edit rewrites only the
+// chunks around it and costs time proportional to the edit and the
+// tokens re-lexed, plus a few operations per chunk, rather than to the
+// size of the document. Edits within the first few bytes, which may
+// change the byte order mark or the detected encoding, lex the whole
+// input again. DocumentTokens returns the tokens of the edited input.
+//
+// Errors and Warnings afterwards describe the whole document, as if it
+// had been lexed from the start. Limits and MaxErrors apply to the whole
+// document too: an edit that takes it past one lexes it again in full
+// with the limits, which stops at the limit as NextToken would, and so
+// does every edit until it is back within them. Update panics on a lexer
+// created with NewLexerFromReader, which does not keep its input.
+func (l *Lexer) Update(edit Range, newText string) TokenEdit {
+	if l.streaming {
+		panic("lexer: Update called on a streaming lexer")
+	}
+	if l.doc == nil {
+		l.doc = l.lexDocument(l.source)
+	}
+	doc := l.doc
+
+	edit.Start = max(0, min(edit.Start, doc.text.size))
+	edit.End = max(edit.Start, min(edit.End, doc.text.size))
+	delta := len(newText) - (edit.End - edit.Start)
+	newEnd := edit.Start + len(newText)
+	oldPrefix := doc.text.slice(0, min(doc.text.size, utf16Prefix))
+
+	// Restart at the first token on the line of the last token starting
+	// before the edit. Tokens can look ahead a few chars but never past
+	// a line ending, and a token spanning lines is that last token itself.
+	// Without such a token, restart at the start of the input.
+	first, position, line, column := cursor{}, 0, 1, 1
+	if before, ok := doc.tokens.prev(doc.tokens.search(edit.Start)); ok {
+		first = before
+		for prev, ok := doc.tokens.prev(first); ok && doc.tokens.at(prev).Line == doc.tokens.at(before).Line; prev, ok = doc.tokens.prev(prev) {
+			first = prev
+		}
+		tok := doc.tokens.at(first)
+		position, line, column = tok.StartOffset, tok.Line, tok.Position
+	} else if strings.HasPrefix(oldPrefix, utf8BOM) {
+		position = len(utf8BOM)
+	}
+	firstTok := doc.tokens.at(first)
+	triviaStart := firstTok.StartOffset - len(firstTok.LeadingTrivia)
+
+	oldEndLine := l.lineAt(doc.text, position, line, edit.End-1)
+	doc.text.replace(edit.Start, edit.End, newText)
+	prefix := doc.text.slice(0, min(doc.text.size, utf16Prefix))
+	if doc.limited || isUTF16(oldPrefix) || isUTF16(prefix) ||
+		edit.Start < len(utf8BOM) && (strings.HasPrefix(oldPrefix, utf8BOM) || strings.HasPrefix(prefix, utf8BOM)) {
+		return l.relex(doc.tokens.index(cursor{chunk: len(doc.tokens.chunks)}))
+	}
+	newEndLine := l.lineAt(doc.text, position, line, newEnd-1)
+
+	sub := l.lexerAt(doc.text, position, triviaStart, line, column)
+	next, end := doc.tokens.search(edit.End), cursor{chunk: len(doc.tokens.chunks)}
+	tokens, diagnostics := []Token{}, []diagnostic{}
+	lineDelta := 0
+	for {
+		errors, warnings := len(sub.errors), len(sub.warnings)
+		tok := sub.NextToken()
+		tokens = append(tokens, tok)
+		diagnostics = sub.diagnosticsSince(diagnostics, tok.StartOffset, errors, warnings)
+		if tok.StartOffset >= newEnd {
+			for doc.tokens.valid(next) && doc.tokens.at(next).StartOffset+delta < tok.StartOffset {
+				next = doc.tokens.next(next)
+			}
+		}
+		if tok.StartOffset >= newEnd && doc.tokens.valid(next) {
+			if old := doc.tokens.at(next); old.StartOffset+delta == tok.StartOffset && old.Type == tok.Type &&
+				old.Literal == tok.Literal && l.resyncs(old, tok, oldEndLine, newEndLine) {
+				// keep the re-lexed token, whose leading trivia may include
+				// the edit, and shift the old ones after it to line up
+				end = doc.tokens.next(next)
+				lineDelta = tok.Line - old.Line
+				doc.tokens.shiftColumns(end, old.Line, tok.Position-old.Position)
+				break
+			}
+		}
+		if tok.Type == EOF {
+			break
+		}
+	}
+
+	result := TokenEdit{Start: doc.tokens.index(first), Tokens: tokens}
+	result.Removed = doc.tokens.index(end) - result.Start
+	doc.tokens.replace(first, end, tokens, diagnostics, delta, lineDelta)
+	if !l.documentWithinLimits(doc) {
+		count := doc.tokens.index(cursor{chunk: len(doc.tokens.chunks)})
+		return l.relex(count - len(tokens) + result.Removed)
+	}
+	l.errors, l.warnings = doc.tokens.diagnostics()
+	return result
+}
+
+// relex lexes the edited text of the document again from the start, in
+// place of its removed tokens.
+func (l *Lexer) relex(removed int) TokenEdit {
+	l.doc = l.lexDocument(l.doc.text.String())
+	return TokenEdit{Start: 0, Removed: removed, Tokens: l.doc.tokens.all()}
+}
+
+// DocumentTokens returns the tokens of the input as edited by Update,
+// in a new slice. Unlike Update it costs time linear in the size of the
+// document.
+func (l *Lexer) DocumentTokens() []Token {
+	if l.doc == nil {
+		l.doc = l.lexDocument(l.source)
+	}
+	return l.doc.tokens.all()
+}
+
+// resyncs reports whether the old tokens can be reused from tok, which
+// matched old. Tabs expand to a width that depends on the column they
+// start at, so with a TabWidth the columns of tokens sharing a line with
+// the edit cannot simply be shifted. The line must start after the edit
+// in both the old and the new input, that is after the lines holding its
+// last byte, since an edit that adds or removes a line ending moves the
+// rest of the line onto a different one.
+func (l *Lexer) resyncs(old, tok Token, oldEndLine, newEndLine int) bool {
+	return l.tabWidth <= 0 || old.Line > oldEndLine && tok.Line > newEndLine
+}
+
+// lineAt returns the line holding offset in text, counting from start,
+// which is on line. It is only needed with a TabWidth, and returns 0
+// otherwise. An offset before start, which only happens when start is
+// the start of the input, is on no line.
+func (l *Lexer) lineAt(text *rope, start int, line int, offset int) int {
+	if l.tabWidth <= 0 {
+		return 0
+	}
+	if offset < start {
+		return line - 1
+	}
+	s := text.slice(start, min(offset+1, text.size))
+	for i := 1; i <= offset-start; i++ {
+		if s[i-1] == '\n' || (s[i-1] == '\r' && (i >= len(s) || s[i] != '\n')) {
+			line++
+		}
+	}
+	return line
+}
+
+// lexDocument lexes all of text with the lexer's settings.
+func (l *Lexer) lexDocument(text string) *document {
+	sub := NewLexerWithOptions(text, l.options())
+	tokens, diagnostics := []Token{}, []diagnostic{}
+	errors, warnings := 0, 0
+	for {
+		tok := sub.NextToken()
+		tokens = append(tokens, tok)
+		// diagnostics about the whole input come with the first token
+		diagnostics = sub.diagnosticsSince(diagnostics, tok.StartOffset, errors, warnings)
+		errors, warnings = len(sub.errors), len(sub.warnings)
+		if tok.Type == EOF {
+			break
+		}
+	}
+	l.errors, l.warnings = sub.errors, sub.warnings
+
+	// lexing stops early at a limit or at MaxErrors, and also at an
+	// unterminated string with StopAtUnterminated, which leaves nothing
+	// further to lex
+	last := tokens[max(len(tokens)-2, 0)]
+	unterminated := l.stringRecovery == StopAtUnterminated && last.Type == STRING && last.Unterminated
+	return &document{
+		text:    newRope(text),
+		tokens:  newTokenStore(tokens, diagnostics),
+		limited: sub.stopped && !unterminated,
+	}
+}
+
+// diagnosticsSince appends the errors and warnings l reported after the
+// given numbers of each to diagnostics, for the token at offset.
+func (l *Lexer) diagnosticsSince(diagnostics []diagnostic, offset int, errors int, warnings int) []diagnostic {
+	for _, err := range l.errors[errors:] {
+		diagnostics = append(diagnostics, diagnostic{offset: offset, err: *err.(*Error)})
+	}
+	for _, err := range l.warnings[warnings:] {
+		diagnostics = append(diagnostics, diagnostic{offset: offset, warning: true, err: *err.(*Error)})
+	}
+	return diagnostics
+}
+
+// lexerAt returns a lexer reading text from position, which must be the
+// start of a token, or of the input after any byte order mark, on line
+// and column. Trivia is collected from triviaStart. The lexer streams
+// text, so only the part it lexes is read.
+func (l *Lexer) lexerAt(text *rope, position int, triviaStart int, line int, column int) *Lexer {
+	base := min(position, triviaStart)
+	sub := &Lexer{
+		base:           base,
+		reader:         text.reader(base),
+		streaming:      true,
+		filename:       l.filename,
+		tabWidth:       l.tabWidth,
+		keywordHook:    l.keywordHook,
+		stringRecovery: l.stringRecovery,
+		skipComments:   l.skipComments,
+		trivia:         l.trivia,
+		triviaStart:    triviaStart,
+		line:           line,
+		lineStart:      position,
+		columnPos:      position,
+		columnNum:      column,
+		lineEnding:     l.lineEnding,
+		lineEndingSet:  true,
+		readPosition:   position,
+		errors:         []error{},
+		warnings:       []error{},
+	}
+	sub.readChar()
+	return sub
+}
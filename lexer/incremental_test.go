@@ -0,0 +1,287 @@
+package lexer
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hculpan/htc/examples"
+)
+
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+func TestUpdateMatchesFullLex(t *testing.T) {
+	tests := []struct {
+		input string
+		edits []textEdit
+	}{
+		{"int x = 1;\nint y = 2;\n", []textEdit{{8, 9, "42"}, {0, 0, "// c\n"}, {14, 15, ""}}},
+		{"a .. b", []textEdit{{4, 4, "."}, {3, 4, ""}}},
+		{"x = 1; /* note */ y = 2;", []textEdit{{9, 9, "*/ z /*"}, {7, 8, ""}}},
+		{"a\nb\nc\n", []textEdit{{1, 2, ""}, {1, 1, "\n\n"}, {0, 0, "q"}}},
+		{"a\r\nb\r\nc", []textEdit{{1, 2, ""}, {2, 2, "\r"}, {4, 5, "x"}}},
+		{"s = \"abc\";\nt = 1;", []textEdit{{5, 5, "\""}, {9, 10, ""}, {4, 5, ""}}},
+		{"a << b", []textEdit{{4, 4, "="}, {2, 3, ""}, {0, 6, "int"}}},
+		{"", []textEdit{{0, 0, "int main() {}"}, {12, 12, " return 0; "}}},
+		{"a b c", []textEdit{{100, 200, " d"}, {3, 1, "x"}}},
+	}
+	for _, ex := range examples.All() {
+		middle := len(ex.Source) / 2
+		tests = append(tests, struct {
+			input string
+			edits []textEdit
+		}{ex.Source, []textEdit{{middle, middle, "\n  x = y + 1;\n"}, {middle - 3, middle + 5, "/* */"}}})
+	}
+
+	for _, tt := range tests {
+		l := NewLexer(tt.input)
+		text := tt.input
+		for _, e := range tt.edits {
+			l.Update(Range{Start: e.start, End: e.end}, e.text)
+			tokens := l.DocumentTokens()
+
+			start := min(max(e.start, 0), len(text))
+			end := min(max(e.end, start), len(text))
+			text = text[:start] + e.text + text[end:]
+			expected := NewLexer(text).Tokens()
+
+			if len(tokens) != len(expected) {
+				t.Errorf("%q: expected %d tokens, got %d", text, len(expected), len(tokens))
+				continue
+			}
+			for idx, tok := range tokens {
+				if tok != expected[idx] {
+					t.Errorf("%q: token %d: expected %+v, got %+v", text, idx, expected[idx], tok)
+				}
+			}
+		}
+	}
+}
+
+func TestUpdateRandomEdits(t *testing.T) {
+	fragments := []string{"a", "1", ";", "=", " ", "\t", "\n", "\r", "\r\n", "\"", "/*", "*/", "//", "0x", "é", "\u0430", "\x00", utf8BOM}
+	for _, opts := range []Options{{}, {TabWidth: 4}, {TabWidth: 8, Trivia: true}} {
+		testRandomEdits(t, opts, fragments, uint64(opts.TabWidth))
+	}
+}
+
+func TestUpdateRandomEditsWithLimits(t *testing.T) {
+	fragments := []string{"a", ";", " ", "\n", "(", ")", "[", "]", "{", "}", "\"", "/*", "@"}
+	limits := []Options{
+		{MaxErrors: 2},
+		{Limits: Limits{MaxTokens: 12}},
+		{Limits: Limits{MaxExpressionNesting: 2, MaxBlockDepth: 1}},
+		{Limits: Limits{MaxFileSize: 24}, MaxErrors: 3},
+		{Limits: Limits{MaxTokens: 10}, StringRecovery: StopAtUnterminated},
+	}
+	for idx, opts := range limits {
+		testRandomEdits(t, opts, fragments, uint64(100+idx))
+	}
+}
+
+// testRandomEdits makes random edits built from fragments and checks the
+// tokens and diagnostics against lexing the edited text from the start.
+func testRandomEdits(t *testing.T, opts Options, fragments []string, seed uint64) {
+	rng := rand.New(rand.NewPCG(1, seed))
+	for run := 0; run < 200; run++ {
+		var sb strings.Builder
+		for range rng.IntN(20) {
+			sb.WriteString(fragments[rng.IntN(len(fragments))])
+		}
+		text := sb.String()
+		l := NewLexerWithOptions(text, opts)
+		tokens := l.DocumentTokens()
+		for range 10 {
+			start := rng.IntN(len(text) + 1)
+			end := start + rng.IntN(len(text)-start+1)
+			newText := fragments[rng.IntN(len(fragments))]
+			previous := tokens
+			edited := l.Update(Range{Start: start, End: end}, newText)
+			tokens = l.DocumentTokens()
+			checkTokenEdit(t, previous, edited, tokens)
+
+			before := text
+			text = text[:start] + newText + text[end:]
+			expected := NewLexerWithOptions(text, opts)
+			expectedTokens := expected.Tokens()
+			if len(tokens) != len(expectedTokens) {
+				t.Fatalf("%+v: %q to %q: expected %d tokens, got %d", opts, before, text, len(expectedTokens), len(tokens))
+			}
+			for idx, tok := range tokens {
+				if tok != expectedTokens[idx] {
+					t.Fatalf("%+v: %q to %q: token %d: expected %+v, got %+v", opts, before, text, idx, expectedTokens[idx], tok)
+				}
+			}
+			if got, want := fmt.Sprint(l.Errors()), fmt.Sprint(expected.Errors()); got != want {
+				t.Fatalf("%+v: %q to %q: expected errors %s, got %s", opts, before, text, want, got)
+			}
+			if got, want := fmt.Sprint(l.Warnings()), fmt.Sprint(expected.Warnings()); got != want {
+				t.Fatalf("%+v: %q to %q: expected warnings %s, got %s", opts, before, text, want, got)
+			}
+		}
+	}
+}
+
+// checkTokenEdit checks that edited turns the tokens before into after:
+// the tokens outside it are kept, with only their positions moved.
+func checkTokenEdit(t *testing.T, before []Token, edited TokenEdit, after []Token) {
+	t.Helper()
+	kept := len(before) - edited.Start - edited.Removed
+	if edited.Start < 0 || kept < 0 || len(after) != edited.Start+len(edited.Tokens)+kept {
+		t.Fatalf("edit %d-%d of %d tokens does not give %d tokens with %d new ones",
+			edited.Start, edited.Start+edited.Removed, len(before), len(after), len(edited.Tokens))
+	}
+	for idx, tok := range after {
+		switch {
+		case idx < edited.Start:
+			if tok != before[idx] {
+				t.Fatalf("token %d: expected %+v to be kept, got %+v", idx, before[idx], tok)
+			}
+		case idx < edited.Start+len(edited.Tokens):
+			if tok != edited.Tokens[idx-edited.Start] {
+				t.Fatalf("token %d: expected %+v from the edit, got %+v", idx, edited.Tokens[idx-edited.Start], tok)
+			}
+		default:
+			old := before[idx-len(edited.Tokens)+edited.Removed]
+			if tok.Type != old.Type || tok.Literal != old.Literal {
+				t.Fatalf("token %d: expected %+v to be moved, got %+v", idx, old, tok)
+			}
+		}
+	}
+}
+
+func TestUpdateLargeDocument(t *testing.T) {
+	// the chunks of text and tokens only show with many of both
+	line := "int value = (a + b) * 42; // comment\n"
+	text := strings.Repeat(line, 2000)
+	l := NewLexerWithOptions(text, Options{TabWidth: 4, Trivia: true})
+	tokens := l.DocumentTokens()
+	rng := rand.New(rand.NewPCG(2, 3))
+	fragments := []string{"x", " ", "\t", "\n", "/*", "*/", "\"", line, strings.Repeat(line, 300)}
+	for range 50 {
+		start := rng.IntN(len(text) + 1)
+		end := start + rng.IntN(min(len(text)-start, 2*len(line))+1)
+		newText := fragments[rng.IntN(len(fragments))]
+		previous := tokens
+		edited := l.Update(Range{Start: start, End: end}, newText)
+		tokens = l.DocumentTokens()
+		checkTokenEdit(t, previous, edited, tokens)
+
+		text = text[:start] + newText + text[end:]
+		expected := NewLexerWithOptions(text, Options{TabWidth: 4, Trivia: true}).Tokens()
+		if len(tokens) != len(expected) {
+			t.Fatalf("edit %d-%d %q: expected %d tokens, got %d", start, end, newText, len(expected), len(tokens))
+		}
+		for idx, tok := range tokens {
+			if tok != expected[idx] {
+				t.Fatalf("edit %d-%d %q: token %d: expected %+v, got %+v", start, end, newText, idx, expected[idx], tok)
+			}
+		}
+	}
+}
+
+func TestUpdateCostsLittle(t *testing.T) {
+	text := strings.Repeat("int value = (a + b) * 42; // comment\n", 30000)
+	l := NewLexer(text)
+	l.Update(Range{}, "")
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := range 100 {
+		middle := len(text)/2 + i
+		l.Update(Range{Start: middle, End: middle}, " ")
+	}
+	runtime.ReadMemStats(&after)
+	// copying the text and the tokens would allocate megabytes per edit
+	if allocated := (after.TotalAlloc - before.TotalAlloc) / 100; allocated > uint64(len(text)/10) {
+		t.Errorf("expected an edit of %d bytes to allocate less than a tenth of it, got %d bytes", len(text), allocated)
+	}
+}
+
+func TestUpdateKeepsErrors(t *testing.T) {
+	input := strings.Repeat("x = \"unterminated\n", 3)
+	l := NewLexer(input)
+	l.Tokens()
+	if len(l.Errors()) != 3 {
+		t.Fatalf("expected 3 errors, got %v", l.Errors())
+	}
+
+	l.Update(Range{Start: 21, End: 21}, "y")
+	expectErrors(t, l, "[1:5] non-terminated string", "[2:6] non-terminated string", "[3:5] non-terminated string")
+}
+
+func TestUpdateKeepsErrorsAfterEdit(t *testing.T) {
+	l := NewLexer("x = \"a\ny = \"b\nz = \"c\n")
+	l.Update(Range{Start: 14, End: 15}, "w")
+	expectErrors(t, l, "[1:5] non-terminated string", "[2:5] non-terminated string", "[3:5] non-terminated string")
+
+	// the errors after the edit move with the text
+	l.Update(Range{Start: 0, End: 0}, "\n")
+	l.Update(Range{Start: 9, End: 9}, "q = ")
+	expectErrors(t, l, "[2:5] non-terminated string", "[3:9] non-terminated string", "[4:5] non-terminated string")
+}
+
+func TestUpdateAppliesLimits(t *testing.T) {
+	l := NewLexerWithOptions("f(a);\n", Options{Limits: Limits{MaxExpressionNesting: 1}})
+	l.Update(Range{Start: 3, End: 3}, "(b)")
+	expectErrors(t, l, "[1:4] expression nesting exceeds the limit of 1")
+	if tokens := l.DocumentTokens(); len(tokens) != 4 || tokens[3].Type != EOF {
+		t.Errorf("expected lexing to stop at the second (, got %+v", tokens)
+	}
+
+	l.Update(Range{Start: 3, End: 6}, "")
+	expectErrors(t, l)
+	if tokens := l.DocumentTokens(); len(tokens) != 6 {
+		t.Errorf("expected all 6 tokens once back within the limit, got %d", len(tokens))
+	}
+}
+
+func TestUpdateRejectedInput(t *testing.T) {
+	// the lexer drops rejected input, but Update edits what was given
+	l := NewLexer("i\x00n\x00t\x00")
+	l.Update(Range{Start: 1, End: 2}, "")
+	if tokens := l.DocumentTokens(); len(tokens) != 5 || tokens[0].Literal != "in" {
+		t.Errorf("expected in, NUL, t, NUL, and EOF, got %+v", tokens)
+	}
+
+	l = NewLexerWithLimits("x = 1;", Limits{MaxFileSize: 5})
+	expectErrors(t, l, "[1:1] input is 6 bytes, exceeding the limit of 5 bytes")
+	l.Update(Range{Start: 1, End: 2}, "")
+	if tokens := l.DocumentTokens(); len(tokens) != 5 {
+		t.Errorf("expected 5 tokens once within the limit, got %+v", tokens)
+	}
+	expectErrors(t, l)
+}
+
+func TestUpdatePanicsOnStreamingLexer(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected Update to panic")
+		}
+	}()
+	NewLexerFromReader(strings.NewReader("a")).Update(Range{}, "b")
+}
+
+func TestUpdateSkipsComments(t *testing.T) {
+	l := NewLexer("a = 1; /* b */\nc = 2;")
+	l.SetSkipComments(true)
+	l.Update(Range{Start: 10, End: 10}, "*/ d /*")
+	tokens := l.DocumentTokens()
+
+	expected := NewLexer("a = 1; /* */ d /*b */\nc = 2;")
+	expected.SetSkipComments(true)
+	expectedTokens := expected.Tokens()
+	if len(tokens) != len(expectedTokens) {
+		t.Fatalf("expected %d tokens, got %d", len(expectedTokens), len(tokens))
+	}
+	for idx, tok := range tokens {
+		if tok != expectedTokens[idx] {
+			t.Errorf("token %d: expected %+v, got %+v", idx, expectedTokens[idx], tok)
+		}
+	}
+}
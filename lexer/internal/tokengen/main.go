@@ -0,0 +1,146 @@
+// Command tokengen generates the operator and keyword tables used by the
+// lexer from the specification in spec.go.
+//
+// It is run by go generate in the lexer package:
+//
+//	go generate ./lexer
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const outputFile = "tokens_gen.go"
+
+func main() {
+	src, err := generate()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(outputFile, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// state is one node of the operator trie.
+type state struct {
+	prefix string
+	name   string // token type accepted in this state, "" if none
+	edges  map[byte]int
+}
+
+// buildStates turns the operator list into a trie. State 0 is the start
+// state, and states are numbered in breadth-first order so the output is
+// stable.
+func buildStates(ops []token) ([]*state, error) {
+	byPrefix := map[string]string{}
+	for _, op := range ops {
+		if op.text == "" {
+			return nil, fmt.Errorf("operator %s has no text", op.name)
+		}
+		if other, ok := byPrefix[op.text]; ok {
+			return nil, fmt.Errorf("operator %q is declared as both %s and %s", op.text, other, op.name)
+		}
+		byPrefix[op.text] = op.name
+	}
+
+	states := []*state{{edges: map[byte]int{}}}
+	for idx := 0; idx < len(states); idx++ {
+		current := states[idx]
+		next := map[byte]bool{}
+		for text := range byPrefix {
+			if len(text) > len(current.prefix) && text[:len(current.prefix)] == current.prefix {
+				next[text[len(current.prefix)]] = true
+			}
+		}
+		chars := []byte{}
+		for ch := range next {
+			chars = append(chars, ch)
+		}
+		sort.Slice(chars, func(i, j int) bool { return chars[i] < chars[j] })
+		for _, ch := range chars {
+			prefix := current.prefix + string(ch)
+			current.edges[ch] = len(states)
+			states = append(states, &state{prefix: prefix, name: byPrefix[prefix], edges: map[byte]int{}})
+		}
+	}
+	return states, nil
+}
+
+func generate() ([]byte, error) {
+	states, err := buildStates(operators)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, tok := range append(append([]token{}, operators...), keywords...) {
+		if seen[tok.name] {
+			return nil, fmt.Errorf("token type %s is declared twice", tok.name)
+		}
+		seen[tok.name] = true
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by tokengen from internal/tokengen/spec.go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package lexer\n\n")
+
+	fmt.Fprintf(&buf, "// Operator token types\n")
+	fmt.Fprintf(&buf, "const (\n")
+	for _, op := range operators {
+		fmt.Fprintf(&buf, "\t%s = %s\n", op.name, strconv.Quote(op.text))
+	}
+	fmt.Fprintf(&buf, ")\n\n")
+
+	fmt.Fprintf(&buf, "// Keyword token types\n")
+	fmt.Fprintf(&buf, "const (\n")
+	for _, kw := range keywords {
+		fmt.Fprintf(&buf, "\t%s = %s\n", kw.name, strconv.Quote(kw.text))
+	}
+	fmt.Fprintf(&buf, ")\n\n")
+
+	fmt.Fprintf(&buf, "// keywords maps each reserved word to its token type.\n")
+	fmt.Fprintf(&buf, "var keywords = map[string]TokenType{\n")
+	for _, kw := range keywords {
+		fmt.Fprintf(&buf, "\t%s: %s,\n", strconv.Quote(kw.text), kw.name)
+	}
+	fmt.Fprintf(&buf, "}\n\n")
+
+	fmt.Fprintf(&buf, "// operatorStates is the operator trie walked by readOperator.\n")
+	fmt.Fprintf(&buf, "var operatorStates = []operatorState{\n")
+	for idx, st := range states {
+		chars := []byte{}
+		for ch := range st.edges {
+			chars = append(chars, ch)
+		}
+		sort.Slice(chars, func(i, j int) bool { return chars[i] < chars[j] })
+		targets := []string{}
+		for _, ch := range chars {
+			targets = append(targets, strconv.Itoa(st.edges[ch]))
+		}
+
+		if idx == 0 {
+			fmt.Fprintf(&buf, "\t// start\n")
+		} else {
+			fmt.Fprintf(&buf, "\t// %s\n", strconv.Quote(st.prefix))
+		}
+		fields := []string{}
+		if st.name != "" {
+			fields = append(fields, "tokenType: "+st.name)
+		}
+		if len(chars) > 0 {
+			fields = append(fields, "edges: "+strconv.Quote(string(chars)))
+			fields = append(fields, "targets: []int{"+strings.Join(targets, ", ")+"}")
+		}
+		fmt.Fprintf(&buf, "\t{%s},\n", strings.Join(fields, ", "))
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	return format.Source(buf.Bytes())
+}
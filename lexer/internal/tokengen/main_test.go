@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGeneratedFileIsCurrent(t *testing.T) {
+	expected, err := generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := os.ReadFile(filepath.Join("..", "..", outputFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current) != string(expected) {
+		t.Errorf("%s is out of date; run go generate ./lexer", outputFile)
+	}
+}
+
+func TestBuildStates(t *testing.T) {
+	states, err := buildStates([]token{{"<", "LT"}, {"<<=", "SHL_EQUALS"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		prefix string
+		name   string
+	}{
+		{"", ""},
+		{"<", "LT"},
+		{"<<", ""},
+		{"<<=", "SHL_EQUALS"},
+	}
+	if len(states) != len(expected) {
+		t.Fatalf("expected %d states, got %d", len(expected), len(states))
+	}
+	for idx, st := range states {
+		if st.prefix != expected[idx].prefix || st.name != expected[idx].name {
+			t.Errorf("state %d: expected %q/%s, got %q/%s", idx, expected[idx].prefix, expected[idx].name, st.prefix, st.name)
+		}
+	}
+}
+
+func TestBuildStatesRejectsDuplicates(t *testing.T) {
+	if _, err := buildStates([]token{{"+", "PLUS"}, {"+", "ADD"}}); err == nil {
+		t.Error("expected error for duplicate operator")
+	}
+}
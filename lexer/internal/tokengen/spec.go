@@ -0,0 +1,95 @@
+package main
+
+// token pairs the source text of an operator or keyword with the name of
+// the token type constant generated for it.
+type token struct {
+	text string
+	name string
+}
+
+// operators lists every operator and punctuation token. The lexer always
+// matches the longest operator, so adding a multi-char operator is a
+// single entry here; its prefixes need not be operators themselves.
+var operators = []token{
+	{"=", "ASSIGN"},
+	{"++", "INCREMENT"},
+	{"--", "DECREMENT"},
+	{"+=", "PLUS_EQUALS"},
+	{"-=", "MINUS_EQUALS"},
+	{"*=", "ASTERISK_EQUALS"},
+	{"/=", "SLASH_EQUALS"},
+	{"%=", "PERCENT_EQUALS"},
+	{"&=", "AMPERSAND_EQUALS"},
+	{"|=", "PIPE_EQUALS"},
+	{"^=", "CARET_EQUALS"},
+	{"<<=", "LSHIFT_EQUALS"},
+	{">>=", "RSHIFT_EQUALS"},
+	{"+", "PLUS"},
+	{"-", "MINUS"},
+	{"*", "ASTERISK"},
+	{"/", "SLASH"},
+	{"%", "PERCENT"},
+	{"!", "BANG"},
+	{"==", "EQ"},
+	{"!=", "NEQ"},
+	{"<", "LT"},
+	{">", "GT"},
+	{"<=", "LE"},
+	{">=", "GE"},
+	{"&&", "AND"},
+	{"||", "OR"},
+	{"&", "AMPERSAND"},
+	{"|", "PIPE"},
+	{"^", "CARET"},
+	{"~", "TILDE"},
+	{"<<", "LSHIFT"},
+	{">>", "RSHIFT"},
+	{"(", "LPAREN"},
+	{")", "RPAREN"},
+	{"[", "LBRACKET"},
+	{"]", "RBRACKET"},
+	{"{", "LBRACE"},
+	{"}", "RBRACE"},
+	{",", "COMMA"},
+	{".", "PERIOD"},
+	{"->", "ARROW"},
+	{"...", "ELLIPSIS"},
+	{";", "SEMICOLON"},
+	{"?", "QUESTION"},
+	{":", "COLON"},
+	{"#", "HASH"},
+}
+
+// keywords lists the reserved words. Any other identifier lexes as IDENT.
+var keywords = []token{
+	{"if", "IF"},
+	{"else", "ELSE"},
+	{"while", "WHILE"},
+	{"return", "RETURN"},
+	{"int", "INT_TYPE"},
+	{"void", "VOID_TYPE"},
+	{"char", "CHAR_TYPE"},
+	{"float", "FLOAT_TYPE"},
+	{"double", "DOUBLE_TYPE"},
+	{"struct", "STRUCT"},
+	{"union", "UNION"},
+	{"enum", "ENUM"},
+	{"typedef", "TYPEDEF"},
+	{"const", "CONST"},
+	{"static", "STATIC"},
+	{"extern", "EXTERN"},
+	{"unsigned", "UNSIGNED"},
+	{"signed", "SIGNED"},
+	{"long", "LONG"},
+	{"short", "SHORT"},
+	{"sizeof", "SIZEOF"},
+	{"for", "FOR"},
+	{"do", "DO"},
+	{"break", "BREAK"},
+	{"continue", "CONTINUE"},
+	{"switch", "SWITCH"},
+	{"case", "CASE"},
+	{"default", "DEFAULT"},
+	{"goto", "GOTO"},
+	{"printf", "PRINTF"},
+}
@@ -0,0 +1,131 @@
+package lexer
+
+import (
+	"encoding/json"
+	"io"
+	"unicode/utf8"
+)
+
+// tokenJSON is the JSON form of a Token. Fields that are usually zero
+// are omitted. JSON strings hold Unicode text, so a literal, trivia or
+// raw text that is not valid UTF-8, such as a string decoded from \xFF
+// or a comment holding raw bytes, is written as base64 in the matching
+// Bytes field instead.
+type tokenJSON struct {
+	Type           TokenType `json:"type"`
+	Literal        string    `json:"literal"`
+	LiteralBytes   []byte    `json:"literalBytes,omitempty"`
+	Line           int       `json:"line"`
+	Column         int       `json:"column"`
+	EndLine        int       `json:"endLine"`
+	EndColumn      int       `json:"endColumn"`
+	StartOffset    int       `json:"startOffset"`
+	EndOffset      int       `json:"endOffset"`
+	Base           int       `json:"base,omitempty"`
+	Suffix         string    `json:"suffix,omitempty"`
+	LeadingTrivia  string    `json:"leadingTrivia,omitempty"`
+	LeadingBytes   []byte    `json:"leadingTriviaBytes,omitempty"`
+	TrailingTrivia string    `json:"trailingTrivia,omitempty"`
+	TrailingBytes  []byte    `json:"trailingTriviaBytes,omitempty"`
+	Raw            string    `json:"raw,omitempty"`
+	RawBytes       []byte    `json:"rawBytes,omitempty"`
+	Unterminated   bool      `json:"unterminated,omitempty"`
+	File           string    `json:"file,omitempty"`
+}
+
+// MarshalJSON encodes tok as a JSON object with camelCase keys, naming
+// Position "column".
+func (tok Token) MarshalJSON() ([]byte, error) {
+	v := tokenJSON{
+		Type:           tok.Type,
+		Literal:        tok.Literal,
+		Line:           tok.Line,
+		Column:         tok.Position,
+		EndLine:        tok.EndLine,
+		EndColumn:      tok.EndColumn,
+		StartOffset:    tok.StartOffset,
+		EndOffset:      tok.EndOffset,
+		Base:           tok.Base,
+		Suffix:         tok.Suffix,
+		LeadingTrivia:  tok.LeadingTrivia,
+		TrailingTrivia: tok.TrailingTrivia,
+		Raw:            tok.Raw,
+		Unterminated:   tok.Unterminated,
+		File:           tok.File,
+	}
+	if !utf8.ValidString(tok.Literal) {
+		v.Literal, v.LiteralBytes = "", []byte(tok.Literal)
+	}
+	if !utf8.ValidString(tok.LeadingTrivia) {
+		v.LeadingTrivia, v.LeadingBytes = "", []byte(tok.LeadingTrivia)
+	}
+	if !utf8.ValidString(tok.TrailingTrivia) {
+		v.TrailingTrivia, v.TrailingBytes = "", []byte(tok.TrailingTrivia)
+	}
+	if !utf8.ValidString(tok.Raw) {
+		v.Raw, v.RawBytes = "", []byte(tok.Raw)
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a token encoded by MarshalJSON.
+func (tok *Token) UnmarshalJSON(data []byte) error {
+	var v tokenJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*tok = Token{
+		Type:           v.Type,
+		Literal:        v.Literal,
+		Line:           v.Line,
+		Position:       v.Column,
+		EndLine:        v.EndLine,
+		EndColumn:      v.EndColumn,
+		StartOffset:    v.StartOffset,
+		EndOffset:      v.EndOffset,
+		Base:           v.Base,
+		Suffix:         v.Suffix,
+		LeadingTrivia:  v.LeadingTrivia,
+		TrailingTrivia: v.TrailingTrivia,
+		Raw:            v.Raw,
+		Unterminated:   v.Unterminated,
+		File:           v.File,
+	}
+	if v.LiteralBytes != nil {
+		tok.Literal = string(v.LiteralBytes)
+	}
+	if v.LeadingBytes != nil {
+		tok.LeadingTrivia = string(v.LeadingBytes)
+	}
+	if v.TrailingBytes != nil {
+		tok.TrailingTrivia = string(v.TrailingBytes)
+	}
+	if v.RawBytes != nil {
+		tok.Raw = string(v.RawBytes)
+	}
+	return nil
+}
+
+// DumpJSON writes tokens to w as a JSON array with one token per line,
+// which keeps dumps readable and easy to diff. Decoding the output into
+// a []Token yields tokens again.
+func DumpJSON(w io.Writer, tokens []Token) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for idx, tok := range tokens {
+		data, err := json.Marshal(tok)
+		if err != nil {
+			return err
+		}
+		sep := ",\n"
+		if idx == 0 {
+			sep = "\n"
+		}
+		if _, err := io.WriteString(w, sep+string(data)); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\n]\n")
+	return err
+}
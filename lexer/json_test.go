@@ -0,0 +1,73 @@
+package lexer
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hculpan/htc/examples"
+)
+
+func TestTokenJSON(t *testing.T) {
+	tok := Token{Type: INT, Literal: "0x1F", Line: 2, Position: 3, EndLine: 2, EndColumn: 7, StartOffset: 9, EndOffset: 13, Base: 16}
+	data, err := json.Marshal(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"type":"INT","literal":"0x1F","line":2,"column":3,"endLine":2,"endColumn":7,"startOffset":9,"endOffset":13,"base":16}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	data, err = json.Marshal(Token{Type: STRING, Literal: "\xff", Unterminated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `{"type":"STRING","literal":"","literalBytes":"/w==","line":0,"column":0,"endLine":0,"endColumn":0,"startOffset":0,"endOffset":0,"unterminated":true}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+}
+
+func TestDumpJSONRoundTrip(t *testing.T) {
+	inputs := []string{"x = \"\\xff\" /* open", "s = \"a\\tb\";\n", "/* \xff */ x // \xfe\n"}
+	for _, ex := range examples.All() {
+		inputs = append(inputs, ex.Source)
+	}
+
+	for _, input := range inputs {
+		l := NewLexerWithOptions(input, Options{Trivia: true})
+		tokens := l.Tokens()
+
+		var sb strings.Builder
+		if err := DumpJSON(&sb, tokens); err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Count(sb.String(), "\n"); lines != len(tokens)+2 {
+			t.Errorf("%q: expected one line per token, got %d lines for %d tokens", input, lines, len(tokens))
+		}
+
+		var decoded []Token
+		if err := json.Unmarshal([]byte(sb.String()), &decoded); err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+		if len(decoded) != len(tokens) {
+			t.Fatalf("%q: expected %d tokens, got %d", input, len(tokens), len(decoded))
+		}
+		for idx, tok := range decoded {
+			if tok != tokens[idx] {
+				t.Errorf("%q: token %d: expected %+v, got %+v", input, idx, tokens[idx], tok)
+			}
+		}
+	}
+}
+
+func TestDumpJSONEmpty(t *testing.T) {
+	var sb strings.Builder
+	if err := DumpJSON(&sb, nil); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != "[\n]\n" {
+		t.Errorf("expected an empty array, got %q", sb.String())
+	}
+}
@@ -0,0 +1,63 @@
+package lexer
+
+import "fmt"
+
+// KeywordHook decides how an identifier that matches a keyword is lexed.
+// It receives the literal and the keyword's token type and returns the
+// token type to use, which is usually either keyword or IDENT.
+type KeywordHook func(literal string, keyword TokenType) TokenType
+
+// SetKeywordHook installs hook to be consulted for every keyword the
+// lexer encounters. It lets later phases or dialects treat keywords
+// contextually. A nil hook restores the default behavior.
+func (l *Lexer) SetKeywordHook(hook KeywordHook) {
+	l.keywordHook = hook
+}
+
+// KeywordsAsIdentifiers returns a hook that lexes the given keywords as
+// IDENT, leaving their resolution to semantic analysis. For example,
+// KeywordsAsIdentifiers(PRINTF) makes printf an ordinary identifier that
+// programs may call or redefine.
+func KeywordsAsIdentifiers(types ...TokenType) KeywordHook {
+	demoted := map[TokenType]bool{}
+	for _, tokenType := range types {
+		demoted[tokenType] = true
+	}
+	return func(literal string, keyword TokenType) TokenType {
+		if demoted[keyword] {
+			return IDENT
+		}
+		return keyword
+	}
+}
+
+// RegisterKeyword makes name a keyword lexed as tokenType, replacing
+// any earlier meaning of name. It lets dialect experiments add words such
+// as bool or repeat without editing the token spec. The keyword table is
+// shared by all lexers, so RegisterKeyword must be called before lexing
+// starts, typically from an init function. It panics if name is not an
+// identifier.
+func RegisterKeyword(name string, tokenType TokenType) {
+	if l := NewLexer(name); !l.isIdentifierStart() || l.readIdentifier() != name {
+		panic(fmt.Sprintf("lexer: keyword %q is not an identifier", name))
+	}
+	keywords[name] = tokenType
+}
+
+// LookupKeyword returns the token type of ident if it is a keyword.
+func LookupKeyword(ident string) (TokenType, bool) {
+	tokenType, ok := keywords[ident]
+	return tokenType, ok
+}
+
+// lookupIdent returns the correct token type for a given identifier.
+func (l *Lexer) lookupIdent(ident string) TokenType {
+	tokenType, ok := LookupKeyword(ident)
+	if !ok {
+		return IDENT
+	}
+	if l.keywordHook != nil {
+		return l.keywordHook(ident, tokenType)
+	}
+	return tokenType
+}
@@ -0,0 +1,230 @@
+package lexer
+
+import (
+	"testing"
+)
+
+func TestKeywordsAsIdentifiers(t *testing.T) {
+	l := NewLexer(`int printf; printf("x"); return 0;`)
+	l.SetKeywordHook(KeywordsAsIdentifiers(PRINTF))
+
+	expected := []ExpectedToken{
+		{Type: "int", Literal: "int"},
+		{Type: "IDENT", Literal: "printf"},
+		{Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "printf"},
+		{Type: "(", Literal: "("},
+		{Type: "STRING", Literal: "x"},
+		{Type: ")", Literal: ")"},
+		{Type: ";", Literal: ";"},
+		{Type: "return", Literal: "return"},
+		{Type: "INT", Literal: "0"},
+		{Type: ";", Literal: ";"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, l, t)
+}
+
+func TestKeywordHook(t *testing.T) {
+	calls := []string{}
+	l := NewLexer("while x for")
+	l.SetKeywordHook(func(literal string, keyword TokenType) TokenType {
+		calls = append(calls, literal)
+		return keyword
+	})
+
+	expected := []ExpectedToken{
+		{Type: "while", Literal: "while"},
+		{Type: "IDENT", Literal: "x"},
+		{Type: "for", Literal: "for"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, l, t)
+	if len(calls) != 2 || calls[0] != "while" || calls[1] != "for" {
+		t.Errorf("expected hook calls for while and for, got %v", calls)
+	}
+}
+
+func TestLookupKeyword(t *testing.T) {
+	if tokenType, ok := LookupKeyword("printf"); !ok || tokenType != PRINTF {
+		t.Errorf("expected printf to be keyword PRINTF, got %s, %v", tokenType, ok)
+	}
+	if _, ok := LookupKeyword("main"); ok {
+		t.Error("expected main not to be a keyword")
+	}
+}
+
+func TestTypeKeywords(t *testing.T) {
+	input := "char c; float f; double d; int i; void v; chars"
+
+	expected := []ExpectedToken{
+		{Type: "char", Literal: "char"},
+		{Type: "IDENT", Literal: "c"},
+		{Type: ";", Literal: ";"},
+		{Type: "float", Literal: "float"},
+		{Type: "IDENT", Literal: "f"},
+		{Type: ";", Literal: ";"},
+		{Type: "double", Literal: "double"},
+		{Type: "IDENT", Literal: "d"},
+		{Type: ";", Literal: ";"},
+		{Type: "int", Literal: "int"},
+		{Type: "IDENT", Literal: "i"},
+		{Type: ";", Literal: ";"},
+		{Type: "void", Literal: "void"},
+		{Type: "IDENT", Literal: "v"},
+		{Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "chars"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, NewLexer(input), t)
+}
+
+func TestAggregateKeywords(t *testing.T) {
+	input := "typedef struct point { int x; } point; union u; enum color;"
+
+	expected := []ExpectedToken{
+		{Type: "typedef", Literal: "typedef"},
+		{Type: "struct", Literal: "struct"},
+		{Type: "IDENT", Literal: "point"},
+		{Type: "{", Literal: "{"},
+		{Type: "int", Literal: "int"},
+		{Type: "IDENT", Literal: "x"},
+		{Type: ";", Literal: ";"},
+		{Type: "}", Literal: "}"},
+		{Type: "IDENT", Literal: "point"},
+		{Type: ";", Literal: ";"},
+		{Type: "union", Literal: "union"},
+		{Type: "IDENT", Literal: "u"},
+		{Type: ";", Literal: ";"},
+		{Type: "enum", Literal: "enum"},
+		{Type: "IDENT", Literal: "color"},
+		{Type: ";", Literal: ";"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, NewLexer(input), t)
+}
+
+func TestControlFlowKeywords(t *testing.T) {
+	input := "do { break; continue; } while (x); switch (x) { case 1: default: }"
+
+	expected := []ExpectedToken{
+		{Type: "do", Literal: "do"},
+		{Type: "{", Literal: "{"},
+		{Type: "break", Literal: "break"},
+		{Type: ";", Literal: ";"},
+		{Type: "continue", Literal: "continue"},
+		{Type: ";", Literal: ";"},
+		{Type: "}", Literal: "}"},
+		{Type: "while", Literal: "while"},
+		{Type: "(", Literal: "("},
+		{Type: "IDENT", Literal: "x"},
+		{Type: ")", Literal: ")"},
+		{Type: ";", Literal: ";"},
+		{Type: "switch", Literal: "switch"},
+		{Type: "(", Literal: "("},
+		{Type: "IDENT", Literal: "x"},
+		{Type: ")", Literal: ")"},
+		{Type: "{", Literal: "{"},
+		{Type: "case", Literal: "case"},
+		{Type: "INT", Literal: "1"},
+		{Type: ":", Literal: ":"},
+		{Type: "default", Literal: "default"},
+		{Type: ":", Literal: ":"},
+		{Type: "}", Literal: "}"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, NewLexer(input), t)
+}
+
+func TestQualifierKeywords(t *testing.T) {
+	input := "const int x; static unsigned long n; extern signed short s;"
+
+	expected := []ExpectedToken{
+		{Type: "const", Literal: "const"},
+		{Type: "int", Literal: "int"},
+		{Type: "IDENT", Literal: "x"},
+		{Type: ";", Literal: ";"},
+		{Type: "static", Literal: "static"},
+		{Type: "unsigned", Literal: "unsigned"},
+		{Type: "long", Literal: "long"},
+		{Type: "IDENT", Literal: "n"},
+		{Type: ";", Literal: ";"},
+		{Type: "extern", Literal: "extern"},
+		{Type: "signed", Literal: "signed"},
+		{Type: "short", Literal: "short"},
+		{Type: "IDENT", Literal: "s"},
+		{Type: ";", Literal: ";"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, NewLexer(input), t)
+}
+
+func TestSizeofKeyword(t *testing.T) {
+	input := "n = sizeof(int) * sizeof x;"
+
+	expected := []ExpectedToken{
+		{Type: "IDENT", Literal: "n"},
+		{Type: "=", Literal: "="},
+		{Type: "sizeof", Literal: "sizeof"},
+		{Type: "(", Literal: "("},
+		{Type: "int", Literal: "int"},
+		{Type: ")", Literal: ")"},
+		{Type: "*", Literal: "*"},
+		{Type: "sizeof", Literal: "sizeof"},
+		{Type: "IDENT", Literal: "x"},
+		{Type: ";", Literal: ";"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, NewLexer(input), t)
+}
+
+func TestGotoAndLabels(t *testing.T) {
+	input := "retry:\n\tgoto retry;"
+
+	expected := []Token{
+		{Type: IDENT, Literal: "retry", Line: 1, Position: 1, EndLine: 1, EndColumn: 6, StartOffset: 0, EndOffset: 5},
+		{Type: COLON, Literal: ":", Line: 1, Position: 6, EndLine: 1, EndColumn: 7, StartOffset: 5, EndOffset: 6},
+		{Type: GOTO, Literal: "goto", Line: 2, Position: 2, EndLine: 2, EndColumn: 6, StartOffset: 8, EndOffset: 12},
+		{Type: IDENT, Literal: "retry", Line: 2, Position: 7, EndLine: 2, EndColumn: 12, StartOffset: 13, EndOffset: 18},
+		{Type: SEMICOLON, Literal: ";", Line: 2, Position: 12, EndLine: 2, EndColumn: 13, StartOffset: 18, EndOffset: 19},
+		{Type: EOF, Literal: "", Line: 2, Position: 13, EndLine: 2, EndColumn: 13, StartOffset: 19, EndOffset: 19},
+	}
+	tokens := NewLexer(input).Tokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d tokens", len(expected), len(tokens))
+	}
+	for idx, tok := range tokens {
+		if tok != expected[idx] {
+			t.Errorf("expected %+v, got %+v", expected[idx], tok)
+		}
+	}
+}
+
+func TestRegisterKeyword(t *testing.T) {
+	const REPEAT = "REPEAT"
+	RegisterKeyword("repeat", REPEAT)
+	RegisterKeyword("printf", IDENT)
+	defer func() {
+		delete(keywords, "repeat")
+		keywords["printf"] = PRINTF
+	}()
+
+	expected := []ExpectedToken{
+		{Type: "REPEAT", Literal: "repeat"},
+		{Type: "IDENT", Literal: "printf"},
+		{Type: "IDENT", Literal: "repeated"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, NewLexer("repeat printf repeated"), t)
+
+	for _, name := range []string{"", "two words", "1st", "+"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected RegisterKeyword(%q) to panic", name)
+				}
+			}()
+			RegisterKeyword(name, REPEAT)
+		}()
+	}
+}
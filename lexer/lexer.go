@@ -1,9 +1,13 @@
 package lexer
 
 import (
-	"errors"
 	"fmt"
+	"io"
+	"iter"
+	"slices"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // TokenType represents the type of token.
@@ -11,89 +15,190 @@ type TokenType string
 
 // Token represents a lexical token.
 type Token struct {
-	Type     TokenType
-	Literal  string
-	Line     int
-	Position int
+	Type           TokenType
+	Literal        string
+	Line           int    // line of the token's first char, starting at 1
+	Position       int    // column of the token's first char in runes, starting at 1
+	EndLine        int    // line of the token's last char; Line for EOF
+	EndColumn      int    // column just after the token's last char; Position for EOF
+	StartOffset    int    // byte offset of the token's first char in the input
+	EndOffset      int    // byte offset just after the token's last char
+	Base           int    // base of an INT literal: 2, 8, 10, or 16; 0 for other tokens
+	Suffix         string // suffix of an INT literal, such as "u" or "UL"; part of Literal
+	LeadingTrivia  string // whitespace and comments before the token, in trivia mode
+	TrailingTrivia string // whitespace and comments after the token on its last line, in trivia mode
+	Raw            string // source text of the token, in trivia mode; differs from Literal for strings
+	Unterminated   bool   // a STRING or COMMENT missing its closing delimiter
+	File           string // name of the input, from Options.Filename
 }
 
-// Token types
+// Error is a diagnostic at a position in the input. Errors and Warnings
+// return *Error values, so tools can place them without parsing the
+// message.
+type Error struct {
+	File   string // name of the input; empty if none was given
+	Line   int    // 0 for diagnostics about the input as a whole
+	Column int
+	Msg    string
+}
+
+// Error formats e as "file:line:col: msg", the form compilers and
+// editors recognize, or as "[line:col] msg" for unnamed input.
+func (e *Error) Error() string {
+	switch {
+	case e.Line == 0 && e.File == "":
+		return e.Msg
+	case e.Line == 0:
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	case e.File == "":
+		return fmt.Sprintf("[%d:%d] %s", e.Line, e.Column, e.Msg)
+	default:
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+	}
+}
+
+// Token types for literals and other tokens that are not operators or
+// keywords. The operator and keyword token types are generated from
+// internal/tokengen/spec.go into tokens_gen.go.
 const (
-	EOF          = "EOF"
-	ILLEGAL      = "ILLEGAL"
-	IDENT        = "IDENT"
-	INT          = "INT"
-	STRING       = "STRING"
-	ASSIGN       = "="
-	INCREMENT    = "++"
-	DECREMENT    = "--"
-	PLUS_EQUALS  = "+="
-	MINUS_EQUALS = "-="
-	PLUS         = "+"
-	MINUS        = "-"
-	ASTERISK     = "*"
-	SLASH        = "/"
-	PERCENT      = "%"
-	BANG         = "!"
-	EQ           = "=="
-	NEQ          = "!="
-	LT           = "<"
-	GT           = ">"
-	LE           = "<="
-	GE           = ">="
-	LPAREN       = "("
-	RPAREN       = ")"
-	LBRACKET     = "["
-	RBRACKET     = "]"
-	LBRACE       = "{"
-	RBRACE       = "}"
-	COMMA        = ","
-	PERIOD       = "."
-	SEMICOLON    = ";"
-	IF           = "if"
-	ELSE         = "else"
-	WHILE        = "while"
-	RETURN       = "return"
-	INT_TYPE     = "int"
-	VOID_TYPE    = "void"
-	FOR          = "for"
-	PRINTF       = "printf"
-	COMMENT      = "COMMENT"
+	EOF     = "EOF"
+	ILLEGAL = "ILLEGAL"
+	IDENT   = "IDENT"
+	INT     = "INT"
+	STRING  = "STRING"
+	COMMENT = "COMMENT"
 )
 
+//go:generate go run ./internal/tokengen
+
+// bytesPerToken estimates the input bytes per token, erring low so a
+// token slice sized from it rarely grows. The example corpus averages a
+// little over three.
+const bytesPerToken = 3
+
+// maxInitialTokens caps the token slice Tokens starts with, so input
+// that lexes to few tokens, such as one long comment, does not reserve
+// a slot for every three bytes. Larger results grow by doubling.
+const maxInitialTokens = 1024
+
 // Lexer represents a lexical scanner.
 type Lexer struct {
-	input         string
-	position      int  // current position in input (points to current char)
-	readPosition  int  // current reading position in input (after current char)
-	ch            byte // current char under examination
-	line          int
-	tokenPosition int
-	errors        []error
+	input          string
+	source         string    // input as given; Update starts from it, as rejected input empties input
+	base           int       // position of input[0]; nonzero once a streamed input is discarded
+	reader         io.Reader // source of further input, nil once exhausted
+	buf            []byte    // streamed input from base; input views it, see fill
+	streaming      bool      // input comes from a reader and is discarded as lexed
+	position       int       // current position in input (points to current char)
+	readPosition   int       // current reading position in input (after current char)
+	ch             byte      // current char under examination
+	line           int
+	lineStart      int // position of the first char of the current line
+	columnPos      int // a position on the current line whose column is known
+	columnNum      int // the column at columnPos
+	lines          *LineIndex
+	lineEnding     LineEnding
+	lineEndingSet  bool
+	filename       string
+	tabWidth       int
+	maxErrors      int
+	limits         Limits
+	keywordHook    KeywordHook
+	stringRecovery StringRecovery
+	skipComments   bool
+	trivia         bool
+	triviaStart    int // position of the first char not yet attached as trivia
+	tokenCount     int
+	parenDepth     int
+	braceDepth     int
+	stopped        bool // set once a limit is exceeded; only EOF follows
+	tokenStart     int  // position of the first char of the current token
+	doc            *document
+	interned       map[string]string // literals of a streaming lexer, see intern
+	peeked         []Token           // tokens lexed ahead by PeekTokenN, returned next
+	errors         []error
+	warnings       []error
 }
 
 // NewLexer initializes a new instance of Lexer.
 func NewLexer(input string) *Lexer {
-	l := &Lexer{input: input}
-	l.line = 1
+	return NewLexerWithOptions(input, Options{})
+}
+
+// Reset prepares l to lex input from the start, as if it were a new
+// Lexer with the same options. Tokens, errors, warnings, and the line
+// index from before are dropped, but buffers are kept for reuse, so a
+// service lexing many files can keep one Lexer per worker.
+func (l *Lexer) Reset(input string) {
+	l.reset(input, l.options())
+}
+
+// reset starts lexing input with opts, keeping only l's reusable
+// buffers.
+func (l *Lexer) reset(input string, opts Options) {
+	l.clear()
+	l.apply(opts)
+	l.source = input
+	if opts.Limits.MaxFileSize > 0 && len(input) > opts.Limits.MaxFileSize {
+		l.addErrorAt(1, 1, fmt.Sprintf("input is %d bytes, exceeding the limit of %d bytes", len(input), opts.Limits.MaxFileSize))
+		l.stopped = true
+		input = ""
+	}
+	l.input = input
+	l.normalize()
+	starts := make([]int, 1, strings.Count(l.input, "\n")+1)
+	starts[0] = l.lineStart
+	l.lines = &LineIndex{input: l.input, starts: starts}
+	l.lineEnding, l.lineEndingSet = detectLineEnding(l.input)
 	l.readChar()
+}
+
+// clear returns l to its zero state apart from the read buffer and the
+// interned literals' map, which are emptied for reuse.
+func (l *Lexer) clear() {
+	buf, interned := l.buf[:0], l.interned
+	clear(interned)
+	*l = Lexer{buf: buf, interned: interned}
+	l.line = 1
 	l.errors = []error{}
-	return l
+	l.warnings = []error{}
 }
 
+// Tokens lexes the rest of the input and returns its tokens, ending with
+// EOF. Token literals are substrings of the input rather than copies, so
+// apart from string literals with escapes the only allocation is the
+// slice, which is sized from the length of the input. A streaming lexer
+// knows only the buffered input, so its slice grows as it goes.
 func (l *Lexer) Tokens() []Token {
-	result := []Token{}
-	var tok Token
-	for {
-		tok = l.NextToken()
-		result = append(result, tok)
-		if tok.Type == EOF {
-			break
+	size := min((l.base+len(l.input)-l.position)/bytesPerToken+1, maxInitialTokens)
+	if l.limits.MaxTokens > 0 {
+		size = min(size, l.limits.MaxTokens+1)
+	}
+	result := make([]Token, 0, size)
+	for tok := range l.Iter() {
+		if len(result) == cap(result) {
+			// double rather than let append grow large slices by a quarter
+			result = slices.Grow(result, len(result))
 		}
+		result = append(result, tok)
 	}
 	return result
 }
 
+// Iter returns an iterator over the remaining tokens, ending with EOF.
+// Tokens are lexed as the loop asks for them, so stopping early leaves
+// the rest of the input unlexed for later calls.
+func (l *Lexer) Iter() iter.Seq[Token] {
+	return func(yield func(Token) bool) {
+		for {
+			tok := l.NextToken()
+			if !yield(tok) || tok.Type == EOF {
+				return
+			}
+		}
+	}
+}
+
 func (l *Lexer) Errors() []error {
 	return l.errors
 }
@@ -102,168 +207,224 @@ func (l *Lexer) HasErrors() bool {
 	return len(l.errors) != 0
 }
 
-func (l *Lexer) addError(msg string) {
-	localMsg := fmt.Sprintf("[%d:%d] ", l.line, l.tokenPosition)
-	localMsg = localMsg + msg
-	l.errors = append(l.errors, errors.New(localMsg))
+// SetSkipComments controls whether comments are dropped instead of being
+// returned as COMMENT tokens. Parsers usually skip them; formatters keep
+// them.
+func (l *Lexer) SetSkipComments(skip bool) {
+	l.skipComments = skip
+}
+
+// Warnings returns diagnostics about suspicious but valid input.
+func (l *Lexer) Warnings() []error {
+	return l.warnings
+}
+
+func (l *Lexer) addWarningAt(line int, column int, msg string) {
+	l.warnings = append(l.warnings, &Error{File: l.filename, Line: line, Column: column, Msg: msg})
+}
+
+func (l *Lexer) addErrorAt(line int, column int, msg string) {
+	if l.maxErrors > 0 && len(l.errors) > l.maxErrors {
+		return
+	}
+	l.errors = append(l.errors, &Error{File: l.filename, Line: line, Column: column, Msg: msg})
+	if l.maxErrors > 0 && len(l.errors) == l.maxErrors {
+		l.errors = append(l.errors, &Error{File: l.filename, Line: line, Column: column, Msg: "too many errors"})
+		l.stopped = true
+	}
 }
 
 // readChar reads the next character and advances the positions in the input.
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
+	if !l.ensure(l.readPosition) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch = l.input[l.readPosition-l.base]
 	}
 	l.position = l.readPosition
 	l.readPosition++
-	l.tokenPosition++
 }
 
-// NextToken lexes the next token from the input.
+// startLine records that a new line begins at position.
+func (l *Lexer) startLine(position int) {
+	l.line++
+	l.lineStart = position
+	if l.lines != nil {
+		l.lines.add(position)
+	}
+}
+
+// atEOF reports whether the whole input has been read.
+func (l *Lexer) atEOF() bool {
+	return !l.ensure(l.position)
+}
+
+// column returns the 1-based column of the current char, counting runes
+// and expanding tabs to the configured width.
+// The column is counted from the last one computed on the line, since a
+// streaming lexer may have discarded the start of the line.
+func (l *Lexer) column() int {
+	if l.columnNum == 0 || l.columnPos < l.lineStart {
+		l.columnPos, l.columnNum = l.lineStart, 1
+	}
+	if l.position > l.columnPos {
+		end := min(l.position, l.base+len(l.input))
+		// positions past the end of the input count as one column each
+		l.columnNum = l.advanceColumn(l.columnNum, l.slice(l.columnPos, end)) + l.position - end
+		l.columnPos = l.position
+	}
+	return l.columnNum
+}
+
+// NextToken returns the next token of the input.
 func (l *Lexer) NextToken() Token {
+	if len(l.peeked) > 0 {
+		tok := l.peeked[0]
+		l.peeked = l.peeked[1:]
+		return tok
+	}
+	return l.lex()
+}
+
+// PeekToken returns the token NextToken will return next without
+// consuming it.
+func (l *Lexer) PeekToken() Token {
+	return l.PeekTokenN(1)
+}
+
+// PeekTokenN returns the nth token NextToken will return, counting from
+// 1, without consuming any. Past the end it returns EOF. Errors and
+// warnings for peeked tokens are recorded when they are peeked. It
+// panics if n is less than 1.
+func (l *Lexer) PeekTokenN(n int) Token {
+	if n < 1 {
+		panic(fmt.Sprintf("lexer: PeekTokenN called with %d", n))
+	}
+	for len(l.peeked) < n {
+		l.peeked = append(l.peeked, l.lex())
+	}
+	return l.peeked[n-1]
+}
+
+// lex lexes the next token from the input.
+func (l *Lexer) lex() Token {
+	if l.stopped {
+		return l.eofToken(l.line, l.column(), l.position)
+	}
+	tok := l.nextToken()
+	for (l.skipComments || l.trivia) && tok.Type == COMMENT {
+		tok = l.nextToken()
+	}
+	if tok.Type == EOF {
+		tok = l.eofToken(tok.Line, tok.Position, l.tokenStart)
+	} else {
+		if l.streaming {
+			tok.Literal = l.intern(tok)
+		}
+		tok.EndLine, tok.EndColumn = l.line, l.column()
+		tok.StartOffset, tok.EndOffset = l.tokenStart, l.position
+		tok.File = l.filename
+		if !l.withinLimits(tok) {
+			l.stopped = true
+			return l.eofToken(tok.Line, tok.Position, tok.StartOffset)
+		}
+	}
+	if l.trivia {
+		l.attachTrivia(&tok)
+	}
+	return tok
+}
+
+// eofToken returns an EOF token with an empty span at the given position.
+func (l *Lexer) eofToken(line int, column int, offset int) Token {
+	return Token{Type: EOF, Line: line, Position: column, EndLine: line, EndColumn: column, StartOffset: offset, EndOffset: offset, File: l.filename}
+}
+
+func (l *Lexer) nextToken() Token {
 	var tok Token
 
 	l.skipWhitespace()
-
-	repeat := false
-	for {
-		repeat = false
-		switch l.ch {
-		case '\n':
-			l.line++
+	for isNewline(l.ch) {
+		l.noteLineEnding()
+		if l.ch == '\r' && l.peekChar() == '\n' {
 			l.readChar()
-			l.skipWhitespace()
-			l.tokenPosition = 0
-			repeat = true
-		case '=':
-			if l.peekChar() == '=' {
-				ch := l.ch
-				l.readChar()
-				tok = Token{Type: EQ, Literal: string(ch) + string(l.ch), Line: l.line, Position: l.tokenPosition}
-			} else {
-				tok = newToken(ASSIGN, l.ch, l.line, l.position)
-			}
-		case '+':
-			if l.peekChar() == '+' {
-				ch := l.ch
-				l.readChar()
-				tok = Token{Type: INCREMENT, Literal: string(ch) + string(l.ch), Line: l.line, Position: l.tokenPosition}
-			} else if l.peekChar() == '=' {
-				ch := l.ch
-				l.readChar()
-				tok = Token{Type: PLUS_EQUALS, Literal: string(ch) + string(l.ch), Line: l.line, Position: l.tokenPosition}
-			} else {
-				tok = newToken(PLUS, l.ch, l.line, l.position)
-			}
-		case '-':
-			if l.peekChar() == '-' {
-				ch := l.ch
-				l.readChar()
-				tok = Token{Type: DECREMENT, Literal: string(ch) + string(l.ch), Line: l.line, Position: l.tokenPosition}
-			} else if l.peekChar() == '=' {
-				ch := l.ch
-				l.readChar()
-				tok = Token{Type: MINUS_EQUALS, Literal: string(ch) + string(l.ch), Line: l.line, Position: l.tokenPosition}
-			} else {
-				tok = newToken(MINUS, l.ch, l.line, l.position)
-			}
-		case '*':
-			tok = newToken(ASTERISK, l.ch, l.line, l.position)
-		case '/':
-			if l.peekChar() == '/' {
-				literal := l.readLineComment()
-				tok.Type = COMMENT
-				tok.Literal = literal
-				tok.Line = l.line
-				tok.Position = l.tokenPosition
-			} else if l.peekChar() == '*' {
-				literal := l.readBlockComment()
-				tok.Type = COMMENT
-				tok.Literal = literal
-				tok.Line = l.line
-				tok.Position = l.tokenPosition
-			} else {
-				tok = newToken(SLASH, l.ch, l.line, l.position)
-			}
-		case '%':
-			tok = newToken(PERCENT, l.ch, l.line, l.position)
-		case '!':
-			if l.peekChar() == '=' {
-				ch := l.ch
-				l.readChar()
-				tok = Token{Type: NEQ, Literal: string(ch) + string(l.ch), Line: l.line, Position: l.tokenPosition}
-			} else {
-				tok = newToken(BANG, l.ch, l.line, l.position)
-			}
-		case '<':
-			if l.peekChar() == '=' {
-				ch := l.ch
-				l.readChar()
-				tok = Token{Type: LE, Literal: string(ch) + string(l.ch), Line: l.line, Position: l.tokenPosition}
-			} else {
-				tok = newToken(LT, l.ch, l.line, l.position)
-			}
-		case '>':
-			if l.peekChar() == '=' {
-				ch := l.ch
-				l.readChar()
-				tok = Token{Type: GE, Literal: string(ch) + string(l.ch), Line: l.line, Position: l.tokenPosition}
-			} else {
-				tok = newToken(GT, l.ch, l.line, l.position)
-			}
-		case '(':
-			tok = newToken(LPAREN, l.ch, l.line, l.position)
-		case ')':
-			tok = newToken(RPAREN, l.ch, l.line, l.position)
-		case '{':
-			tok = newToken(LBRACE, l.ch, l.line, l.position)
-		case '}':
-			tok = newToken(RBRACE, l.ch, l.line, l.position)
-		case '[':
-			tok = newToken(LBRACKET, l.ch, l.line, l.position)
-		case ']':
-			tok = newToken(RBRACKET, l.ch, l.line, l.position)
-		case ',':
-			tok = newToken(COMMA, l.ch, l.line, l.position)
-		case '.':
-			tok = newToken(PERIOD, l.ch, l.line, l.position)
-		case ';':
-			tok = newToken(SEMICOLON, l.ch, l.line, l.position)
-		case 0:
-			tok.Literal = ""
-			tok.Type = EOF
-		case '"':
-			literal, err := l.readString()
-			if err != nil {
-				l.addError(err.Error())
-			}
-			tok.Type = STRING
-			tok.Literal = literal
-			tok.Line = l.line
-			tok.Position = l.tokenPosition
-		default:
-			if isLetter(l.ch) {
-				literal := l.readIdentifier()
-				tok.Type = lookupIdent(literal)
-				tok.Literal = literal
-				tok.Line = l.line
-				tok.Position = l.tokenPosition
-				return tok
-			} else if isDigit(l.ch) {
-				tok.Type = INT
-				tok.Literal = l.readNumber()
-				tok.Line = l.line
-				tok.Position = l.tokenPosition
-				return tok
-			} else {
-				tok = newToken(ILLEGAL, l.ch, l.line, l.position)
-			}
 		}
+		l.readChar()
+		l.startLine(l.position)
+		l.skipWhitespace()
+	}
 
-		if !repeat {
+	l.discard()
+	l.tokenStart = l.position
+	line, column := l.line, l.column()
+	switch l.ch {
+	case '/':
+		if l.peekChar() == '/' {
+			literal := l.readLineComment()
+			tok.Type = COMMENT
+			tok.Literal = literal
+			tok.Line = line
+			tok.Position = column
+			return tok
+		} else if l.peekChar() == '*' {
+			literal, unterminated := l.readBlockComment(line, column)
+			tok.Type = COMMENT
+			tok.Literal = literal
+			tok.Unterminated = unterminated
+			tok.Line = line
+			tok.Position = column
+			return tok
+		}
+		tok = l.readOperator(line, column)
+	case 0:
+		if !l.atEOF() {
+			// a NUL byte inside the input
+			tok = newToken(ILLEGAL, l.ch, line, column)
 			break
 		}
+		tok.Literal = ""
+		tok.Type = EOF
+		tok.Line = line
+		tok.Position = column
+		// stay at the end so later calls return the same EOF
+		return tok
+	case '"':
+		literal, unterminated := l.readString(line, column)
+		tok.Type = STRING
+		tok.Literal = literal
+		tok.Line = line
+		tok.Position = column
+		tok.Unterminated = unterminated
+		if unterminated && l.stringRecovery == StopAtUnterminated {
+			l.stopped = true
+		}
+		if l.ch != '"' {
+			// leave the newline for the next call so line counting stays correct
+			return tok
+		}
+	default:
+		if l.isIdentifierStart() {
+			literal := l.normalizeIdentifier(l.readIdentifier(), line, column)
+			tok.Type = l.lookupIdent(literal)
+			tok.Literal = literal
+			tok.Line = line
+			tok.Position = column
+			return tok
+		} else if isDigit(l.ch) {
+			literal, base, suffix, err := l.readNumber()
+			tok.Type = INT
+			tok.Literal = literal
+			tok.Line = line
+			tok.Position = column
+			tok.Base = base
+			tok.Suffix = suffix
+			if err != nil {
+				l.addErrorAt(line, column, err.Error())
+			}
+			return tok
+		} else {
+			tok = l.readOperator(line, column)
+		}
 	}
 	l.readChar()
 	return tok
@@ -276,104 +437,201 @@ func newToken(tokenType TokenType, ch byte, line int, position int) Token {
 
 func (l *Lexer) readLineComment() string {
 	position := l.position
-	for l.ch != '\r' && l.ch != '\n' {
+	for !isNewline(l.ch) && !l.atEOF() {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	return l.slice(position, l.position)
 }
 
-func (l *Lexer) readBlockComment() string {
+// readBlockComment reads a block comment starting at line and column.
+// A comment missing its closing */ is reported and runs to the end of
+// the input.
+func (l *Lexer) readBlockComment(line int, column int) (string, bool) {
 	position := l.position
+	// skip the opening /* so its * cannot also close the comment
+	l.readChar()
+	l.readChar()
 	for {
-		if l.ch == '*' && l.peekChar() == '/' {
+		if l.atEOF() {
+			l.addErrorAt(line, column, "non-terminated block comment")
+			return l.slice(position, l.position), true
+		} else if l.ch == '*' && l.peekChar() == '/' {
 			break
-		} else if l.ch == '\n' {
-			l.line++
+		} else if isNewline(l.ch) {
+			l.noteLineEnding()
+			if l.ch == '\r' && l.peekChar() == '\n' {
+				l.readChar()
+			}
+			l.startLine(l.position + 1)
 		}
 		l.readChar()
 	}
 	l.readChar()
 	l.readChar()
-	return l.input[position:l.position]
+	return l.slice(position, l.position), false
 }
 
-func (l *Lexer) readString() (string, error) {
-	position := l.position
+// readString reads a string literal starting at line and column and
+// returns its decoded value and whether it is unterminated. Invalid
+// escape sequences are reported and kept as written. An unterminated
+// literal ends at its line ending, or with RecoverAtQuote at the next
+// quote, and the lexer is left on the line ending or quote.
+func (l *Lexer) readString(line int, column int) (string, bool) {
 	l.readChar()
+	position := l.position
+	for l.ch != '"' && l.ch != '\\' && !isNewline(l.ch) && !l.atEOF() {
+		l.readChar()
+	}
+	if l.ch == '"' {
+		// without escapes the value is the text between the quotes
+		return l.slice(position, l.position), false
+	}
+
+	var sb strings.Builder
+	sb.WriteString(l.slice(position, l.position))
+	unterminated := false
 	for l.ch != '"' {
-		if l.ch == '\n' {
-			return l.input[position+1 : l.position], errors.New("non-terminated string")
+		if l.atEOF() {
+			if !unterminated {
+				l.addErrorAt(line, column, "non-terminated string")
+			}
+			return sb.String(), true
+		} else if isNewline(l.ch) {
+			if !unterminated {
+				l.addErrorAt(line, column, "non-terminated string")
+			}
+			if l.stringRecovery != RecoverAtQuote {
+				return sb.String(), true
+			}
+			unterminated = true
+			l.readStringNewline(&sb)
+			continue
+		} else if l.ch == '\\' {
+			l.readEscape(&sb)
+			continue
 		}
+		sb.WriteByte(l.ch)
 		l.readChar()
 	}
 
-	return l.input[position+1 : l.position], nil
+	return sb.String(), unterminated
 }
 
-// readIdentifier reads an identifier starting with a letter.
-func (l *Lexer) readIdentifier() string {
-	position := l.position
-	for isLetter(l.ch) {
+// readStringNewline adds the line ending at the current char to sb and
+// advances past it, for a string continuing onto the next line.
+func (l *Lexer) readStringNewline(sb *strings.Builder) {
+	l.noteLineEnding()
+	if l.ch == '\r' && l.peekChar() == '\n' {
+		sb.WriteByte(l.ch)
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	sb.WriteByte(l.ch)
+	l.readChar()
+	l.startLine(l.position)
 }
 
-// readNumber reads a number starting with a digit.
-func (l *Lexer) readNumber() string {
-	position := l.position
-	for isDigit(l.ch) {
+// readEscape decodes the escape sequence starting at the current
+// backslash into sb and advances past it.
+func (l *Lexer) readEscape(sb *strings.Builder) {
+	line, column := l.line, l.column()
+	l.readChar()
+	switch l.ch {
+	case 'n':
+		sb.WriteByte('\n')
+	case 't':
+		sb.WriteByte('\t')
+	case 'r':
+		sb.WriteByte('\r')
+	case '0':
+		sb.WriteByte(0)
+	case '\\', '"', '\'':
+		sb.WriteByte(l.ch)
+	case 'x':
+		hi, lo := digitValue(l.peekCharN(1)), digitValue(l.peekCharN(2))
+		if hi >= 16 || lo >= 16 {
+			l.addErrorAt(line, column, "\\x escape requires two hex digits")
+			sb.WriteString("\\x")
+			break
+		}
+		sb.WriteByte(byte(hi<<4 | lo))
 		l.readChar()
+		l.readChar()
+	default:
+		sb.WriteByte('\\')
+		if isNewline(l.ch) || l.atEOF() {
+			// let readString report the unterminated literal
+			return
+		}
+		l.addErrorAt(line, column, fmt.Sprintf("invalid escape sequence '\\%c'", l.ch))
+		sb.WriteByte(l.ch)
 	}
-	return l.input[position:l.position]
+	l.readChar()
+}
+
+// readIdentifier reads an identifier starting with a letter. Later chars
+// may also be digits or, for non-ASCII input decoded as UTF-8, combining
+// marks.
+func (l *Lexer) readIdentifier() string {
+	position := l.position
+	for {
+		if isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
+		} else if r, size := l.peekRune(); r >= utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)) {
+			for i := 0; i < size; i++ {
+				l.readChar()
+			}
+		} else {
+			break
+		}
+	}
+	return l.slice(position, l.position)
+}
+
+// peekRune decodes the UTF-8 encoded rune starting at the current char.
+func (l *Lexer) peekRune() (rune, int) {
+	l.ensure(l.position + utf8.UTFMax - 1)
+	if l.atEOF() {
+		return 0, 0
+	}
+	return utf8.DecodeRuneInString(l.input[l.position-l.base:])
+}
+
+// isIdentifierStart checks if the current char begins an identifier.
+func (l *Lexer) isIdentifierStart() bool {
+	if l.ch < utf8.RuneSelf {
+		return isLetter(l.ch)
+	}
+	r, _ := l.peekRune()
+	return unicode.IsLetter(r)
 }
 
 // peekChar returns the next character without advancing the position.
 func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
+	if !l.ensure(l.readPosition) {
 		return 0
 	} else {
-		return l.input[l.readPosition]
+		return l.input[l.readPosition-l.base]
 	}
 }
 
 // skipWhitespace skips any whitespace characters.
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
+	for l.ch == ' ' || l.ch == '\t' {
 		l.readChar()
 	}
 }
 
-// isLetter checks if the character is a letter.
+// isNewline checks if the character starts a line ending.
+func isNewline(ch byte) bool {
+	return ch == '\n' || ch == '\r'
+}
+
+// isLetter checks if the character is an ASCII letter or underscore.
 func isLetter(ch byte) bool {
-	return unicode.IsLetter(rune(ch)) || ch == '_'
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
 
 // isDigit checks if the character is a digit.
 func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
-
-// lookupIdent returns the correct token type for a given identifier.
-func lookupIdent(ident string) TokenType {
-	switch ident {
-	case "if":
-		return IF
-	case "else":
-		return ELSE
-	case "while":
-		return WHILE
-	case "return":
-		return RETURN
-	case "int":
-		return INT_TYPE
-	case "void":
-		return VOID_TYPE
-	case "for":
-		return FOR
-	case "printf":
-		return PRINTF
-	default:
-		return IDENT
-	}
-}
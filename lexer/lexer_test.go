@@ -1,7 +1,10 @@
 package lexer
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/hculpan/htc/examples"
 )
 
 type ExpectedToken struct {
@@ -38,6 +41,52 @@ func TestLexerComments(t *testing.T) {
 	validateTokens(expected, lexer, t)
 }
 
+func TestLexerSkipComments(t *testing.T) {
+	input := "int i; // note\n/* a\nb */ i = /**/ 0;"
+
+	expected := []ExpectedToken{
+		{Type: "int", Literal: "int"},
+		{Type: "IDENT", Literal: "i"},
+		{Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "i"},
+		{Type: "=", Literal: "="},
+		{Type: "INT", Literal: "0"},
+		{Type: ";", Literal: ";"},
+		{Type: "EOF", Literal: ""},
+	}
+
+	lexer := NewLexer(input)
+	lexer.SetSkipComments(true)
+	validateTokens(expected, lexer, t)
+}
+
+func TestLexerUnterminatedBlockComment(t *testing.T) {
+	inputs := map[string]string{
+		"x = 1;\n  /* open\n*":  "/* open\n*",
+		"x = 1;\n  /*/":         "/*/",
+		"x = 1;\n  /* open\r\n": "/* open\r\n",
+	}
+
+	for input, comment := range inputs {
+		l := NewLexer(input)
+		expected := []ExpectedToken{
+			{Type: "IDENT", Literal: "x"},
+			{Type: "=", Literal: "="},
+			{Type: "INT", Literal: "1"},
+			{Type: ";", Literal: ";"},
+			{Type: "COMMENT", Literal: comment},
+			{Type: "EOF", Literal: ""},
+		}
+		validateTokens(expected, l, t)
+		expectErrors(t, l, "[2:3] non-terminated block comment")
+
+		tokens := NewLexer(input).Tokens()
+		if !tokens[4].Unterminated {
+			t.Errorf("%q: expected the comment to be marked unterminated", input)
+		}
+	}
+}
+
 func TestLexerMiscCharacters(t *testing.T) {
 	input := `
 	int i = 0;
@@ -74,6 +123,157 @@ func TestLexerMiscCharacters(t *testing.T) {
 	validateTokens(expected, lexer, t)
 }
 
+func TestLexerLongestOperator(t *testing.T) {
+	input := "a<=b==c!d/* x */e--f-=g"
+
+	expected := []ExpectedToken{
+		{Type: "IDENT", Literal: "a"},
+		{Type: "<=", Literal: "<="},
+		{Type: "IDENT", Literal: "b"},
+		{Type: "==", Literal: "=="},
+		{Type: "IDENT", Literal: "c"},
+		{Type: "!", Literal: "!"},
+		{Type: "IDENT", Literal: "d"},
+		{Type: "COMMENT", Literal: "/* x */"},
+		{Type: "IDENT", Literal: "e"},
+		{Type: "--", Literal: "--"},
+		{Type: "IDENT", Literal: "f"},
+		{Type: "-=", Literal: "-="},
+		{Type: "IDENT", Literal: "g"},
+		{Type: "EOF", Literal: ""},
+	}
+
+	validateTokens(expected, NewLexer(input), t)
+}
+
+func TestLexerLogicalOperators(t *testing.T) {
+	input := "if (a > 0 && b < 10 || !c)"
+
+	expected := []ExpectedToken{
+		{Type: "if", Literal: "if"},
+		{Type: "(", Literal: "("},
+		{Type: "IDENT", Literal: "a"},
+		{Type: ">", Literal: ">"},
+		{Type: "INT", Literal: "0"},
+		{Type: "&&", Literal: "&&"},
+		{Type: "IDENT", Literal: "b"},
+		{Type: "<", Literal: "<"},
+		{Type: "INT", Literal: "10"},
+		{Type: "||", Literal: "||"},
+		{Type: "!", Literal: "!"},
+		{Type: "IDENT", Literal: "c"},
+		{Type: ")", Literal: ")"},
+		{Type: "EOF", Literal: ""},
+	}
+
+	validateTokens(expected, NewLexer(input), t)
+}
+
+func TestLexerBitwiseOperators(t *testing.T) {
+	input := "a & b | c ^ ~d << 2 >> e < f <= g > h >= i &&& |||"
+
+	expected := []ExpectedToken{
+		{Type: "IDENT", Literal: "a"},
+		{Type: "&", Literal: "&"},
+		{Type: "IDENT", Literal: "b"},
+		{Type: "|", Literal: "|"},
+		{Type: "IDENT", Literal: "c"},
+		{Type: "^", Literal: "^"},
+		{Type: "~", Literal: "~"},
+		{Type: "IDENT", Literal: "d"},
+		{Type: "<<", Literal: "<<"},
+		{Type: "INT", Literal: "2"},
+		{Type: ">>", Literal: ">>"},
+		{Type: "IDENT", Literal: "e"},
+		{Type: "<", Literal: "<"},
+		{Type: "IDENT", Literal: "f"},
+		{Type: "<=", Literal: "<="},
+		{Type: "IDENT", Literal: "g"},
+		{Type: ">", Literal: ">"},
+		{Type: "IDENT", Literal: "h"},
+		{Type: ">=", Literal: ">="},
+		{Type: "IDENT", Literal: "i"},
+		{Type: "&&", Literal: "&&"},
+		{Type: "&", Literal: "&"},
+		{Type: "||", Literal: "||"},
+		{Type: "|", Literal: "|"},
+		{Type: "EOF", Literal: ""},
+	}
+
+	validateTokens(expected, NewLexer(input), t)
+}
+
+func TestLexerCompoundAssignment(t *testing.T) {
+	input := "a += 1; a -= 1; a *= 2; a /= 2; a %= 3; a &= m; a |= m; a ^= m; a <<= 1; a >>= 1; a /=/**/b"
+
+	expected := []ExpectedToken{
+		{Type: "IDENT", Literal: "a"}, {Type: "+=", Literal: "+="}, {Type: "INT", Literal: "1"}, {Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "a"}, {Type: "-=", Literal: "-="}, {Type: "INT", Literal: "1"}, {Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "a"}, {Type: "*=", Literal: "*="}, {Type: "INT", Literal: "2"}, {Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "a"}, {Type: "/=", Literal: "/="}, {Type: "INT", Literal: "2"}, {Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "a"}, {Type: "%=", Literal: "%="}, {Type: "INT", Literal: "3"}, {Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "a"}, {Type: "&=", Literal: "&="}, {Type: "IDENT", Literal: "m"}, {Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "a"}, {Type: "|=", Literal: "|="}, {Type: "IDENT", Literal: "m"}, {Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "a"}, {Type: "^=", Literal: "^="}, {Type: "IDENT", Literal: "m"}, {Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "a"}, {Type: "<<=", Literal: "<<="}, {Type: "INT", Literal: "1"}, {Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "a"}, {Type: ">>=", Literal: ">>="}, {Type: "INT", Literal: "1"}, {Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "a"}, {Type: "/=", Literal: "/="}, {Type: "COMMENT", Literal: "/**/"}, {Type: "IDENT", Literal: "b"},
+		{Type: "EOF", Literal: ""},
+	}
+
+	validateTokens(expected, NewLexer(input), t)
+}
+
+func TestLexerConditionalOperator(t *testing.T) {
+	input := "max = a > b ? a : b;"
+
+	expected := []ExpectedToken{
+		{Type: "IDENT", Literal: "max"},
+		{Type: "=", Literal: "="},
+		{Type: "IDENT", Literal: "a"},
+		{Type: ">", Literal: ">"},
+		{Type: "IDENT", Literal: "b"},
+		{Type: "?", Literal: "?"},
+		{Type: "IDENT", Literal: "a"},
+		{Type: ":", Literal: ":"},
+		{Type: "IDENT", Literal: "b"},
+		{Type: ";", Literal: ";"},
+		{Type: "EOF", Literal: ""},
+	}
+
+	validateTokens(expected, NewLexer(input), t)
+}
+
+func TestLexerArrowAndEllipsis(t *testing.T) {
+	input := "p->x - -y--; f(int n, ...); a..b"
+
+	expected := []ExpectedToken{
+		{Type: "IDENT", Literal: "p"},
+		{Type: "->", Literal: "->"},
+		{Type: "IDENT", Literal: "x"},
+		{Type: "-", Literal: "-"},
+		{Type: "-", Literal: "-"},
+		{Type: "IDENT", Literal: "y"},
+		{Type: "--", Literal: "--"},
+		{Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "f"},
+		{Type: "(", Literal: "("},
+		{Type: "int", Literal: "int"},
+		{Type: "IDENT", Literal: "n"},
+		{Type: ",", Literal: ","},
+		{Type: "...", Literal: "..."},
+		{Type: ")", Literal: ")"},
+		{Type: ";", Literal: ";"},
+		{Type: "IDENT", Literal: "a"},
+		{Type: ".", Literal: "."},
+		{Type: ".", Literal: "."},
+		{Type: "IDENT", Literal: "b"},
+		{Type: "EOF", Literal: ""},
+	}
+
+	validateTokens(expected, NewLexer(input), t)
+}
+
 func TestLexerStrings(t *testing.T) {
 	input := `
 		"Test one "
@@ -85,7 +285,7 @@ func TestLexerStrings(t *testing.T) {
 
 	expected := []ExpectedToken{
 		{Type: "STRING", Literal: "Test one "},
-		{Type: "STRING", Literal: "another \\ttest \\n"},
+		{Type: "STRING", Literal: "another \ttest \n"},
 		{Type: "STRING", Literal: "a final test"},
 		{Type: "STRING", Literal: "test"},
 		{Type: ",", Literal: ","},
@@ -102,8 +302,8 @@ func TestLexerStrings(t *testing.T) {
 		if len(lexer.Errors()) != 1 {
 			t.Errorf("expected 1 error, found %d", len(lexer.Errors()))
 		}
-		if lexer.Errors()[0].Error() != "[4:13] non-terminated string" {
-			t.Errorf("error expected '[4:13] non-terminated string', got '%s'", lexer.Errors()[0].Error())
+		if lexer.Errors()[0].Error() != "[4:3] non-terminated string" {
+			t.Errorf("error expected '[4:3] non-terminated string', got '%s'", lexer.Errors()[0].Error())
 		}
 	}
 }
@@ -177,7 +377,7 @@ func TestLexerFactorialProgram(t *testing.T) {
 		{Type: ")", Literal: ")"},
 		{Type: "printf", Literal: "printf"},
 		{Type: "(", Literal: "("},
-		{Type: "STRING", Literal: "Factorial of %d is %d\\n"},
+		{Type: "STRING", Literal: "Factorial of %d is %d\n"},
 		{Type: ",", Literal: ","},
 		{Type: "IDENT", Literal: "i"},
 		{Type: ",", Literal: ","},
@@ -198,6 +398,34 @@ func TestLexerFactorialProgram(t *testing.T) {
 	validateTokens(expected, lexer, t)
 }
 
+func TestLexerPositions(t *testing.T) {
+	input := "int x;\n  x += 10; // note\n/* a\nb */ \"s\"\n"
+
+	expected := []Token{
+		{Type: INT_TYPE, Literal: "int", Line: 1, Position: 1, EndLine: 1, EndColumn: 4, StartOffset: 0, EndOffset: 3},
+		{Type: IDENT, Literal: "x", Line: 1, Position: 5, EndLine: 1, EndColumn: 6, StartOffset: 4, EndOffset: 5},
+		{Type: SEMICOLON, Literal: ";", Line: 1, Position: 6, EndLine: 1, EndColumn: 7, StartOffset: 5, EndOffset: 6},
+		{Type: IDENT, Literal: "x", Line: 2, Position: 3, EndLine: 2, EndColumn: 4, StartOffset: 9, EndOffset: 10},
+		{Type: PLUS_EQUALS, Literal: "+=", Line: 2, Position: 5, EndLine: 2, EndColumn: 7, StartOffset: 11, EndOffset: 13},
+		{Type: INT, Literal: "10", Line: 2, Position: 8, EndLine: 2, EndColumn: 10, StartOffset: 14, EndOffset: 16, Base: 10},
+		{Type: SEMICOLON, Literal: ";", Line: 2, Position: 10, EndLine: 2, EndColumn: 11, StartOffset: 16, EndOffset: 17},
+		{Type: COMMENT, Literal: "// note", Line: 2, Position: 12, EndLine: 2, EndColumn: 19, StartOffset: 18, EndOffset: 25},
+		{Type: COMMENT, Literal: "/* a\nb */", Line: 3, Position: 1, EndLine: 4, EndColumn: 5, StartOffset: 26, EndOffset: 35},
+		{Type: STRING, Literal: "s", Line: 4, Position: 6, EndLine: 4, EndColumn: 9, StartOffset: 36, EndOffset: 39},
+		{Type: EOF, Literal: "", Line: 5, Position: 1, EndLine: 5, EndColumn: 1, StartOffset: 40, EndOffset: 40},
+	}
+
+	tokens := NewLexer(input).Tokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d tokens", len(expected), len(tokens))
+	}
+	for idx, tok := range tokens {
+		if tok != expected[idx] {
+			t.Errorf("expected %+v, got %+v", expected[idx], tok)
+		}
+	}
+}
+
 func validateTokens(expected []ExpectedToken, lexer *Lexer, t *testing.T) {
 	tokens := lexer.Tokens()
 	if len(tokens) != len(expected) {
@@ -214,3 +442,136 @@ func validateTokens(expected []ExpectedToken, lexer *Lexer, t *testing.T) {
 		}
 	}
 }
+
+func TestTokenSpans(t *testing.T) {
+	input := "/* a\r\n\r\nb */ \"open\r\nx"
+
+	expected := []Token{
+		{Type: COMMENT, Literal: "/* a\r\n\r\nb */", Line: 1, Position: 1, EndLine: 3, EndColumn: 5, StartOffset: 0, EndOffset: 12},
+		{Type: STRING, Literal: "open", Line: 3, Position: 6, EndLine: 3, EndColumn: 11, StartOffset: 13, EndOffset: 18, Unterminated: true},
+		{Type: IDENT, Literal: "x", Line: 4, Position: 1, EndLine: 4, EndColumn: 2, StartOffset: 20, EndOffset: 21},
+		{Type: EOF, Literal: "", Line: 4, Position: 2, EndLine: 4, EndColumn: 2, StartOffset: 21, EndOffset: 21},
+	}
+
+	tokens := NewLexer(input).Tokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d tokens", len(expected), len(tokens))
+	}
+	for idx, tok := range tokens {
+		if tok != expected[idx] {
+			t.Errorf("expected %+v, got %+v", expected[idx], tok)
+		}
+	}
+}
+
+func TestTokenOffsetsSliceSource(t *testing.T) {
+	inputs := []string{"\xef\xbb\xbfint x = 0x1F; // c\r\n\"a\\tb\" /* d */"}
+	for _, ex := range examples.All() {
+		inputs = append(inputs, ex.Source)
+	}
+
+	for _, input := range inputs {
+		for _, tok := range NewLexer(input).Tokens() {
+			text := input[tok.StartOffset:tok.EndOffset]
+			if tok.Type == STRING {
+				text = NewLexer(text).NextToken().Literal
+			}
+			if text != tok.Literal {
+				t.Errorf("offsets of %+v give %q", tok, text)
+			}
+		}
+	}
+}
+
+func TestLexerPeekToken(t *testing.T) {
+	l := NewLexer("a = b;")
+
+	if tok := l.PeekTokenN(3); tok.Type != IDENT || tok.Literal != "b" {
+		t.Errorf("expected third token b, got %+v", tok)
+	}
+	if tok := l.PeekToken(); tok.Type != IDENT || tok.Literal != "a" {
+		t.Errorf("expected next token a, got %+v", tok)
+	}
+	if tok := l.PeekTokenN(10); tok.Type != EOF {
+		t.Errorf("expected EOF past the end, got %+v", tok)
+	}
+	validateTokens(expectedTokensFor("a = b;"), l, t)
+	if tok := l.PeekToken(); tok.Type != EOF {
+		t.Errorf("expected EOF after the input, got %+v", tok)
+	}
+}
+
+func TestLexerPeekTokenPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected PeekTokenN(0) to panic")
+		}
+	}()
+	NewLexer("a").PeekTokenN(0)
+}
+
+func TestLexerIter(t *testing.T) {
+	l := NewLexer("a = b;")
+
+	var literals []string
+	for tok := range l.Iter() {
+		literals = append(literals, tok.Literal)
+		if tok.Type == ASSIGN {
+			break
+		}
+	}
+	if strings.Join(literals, " ") != "a =" {
+		t.Errorf("expected the loop to stop after =, got %q", literals)
+	}
+
+	count := 0
+	for tok := range l.Iter() {
+		count++
+		if count == 3 && tok.Type != EOF {
+			t.Errorf("expected EOF third, got %+v", tok)
+		}
+	}
+	if count != 3 {
+		t.Errorf("expected 3 remaining tokens, got %d", count)
+	}
+}
+
+func TestLexerReset(t *testing.T) {
+	opts := Options{Filename: "a.c", SkipComments: true, TabWidth: 4}
+	l := NewLexerWithOptions("x = \"open\n", opts)
+	l.PeekToken()
+	l.Tokens()
+
+	inputs := []string{"\ty = 2; // c\n", "x = \"open\n"}
+	for _, input := range inputs {
+		l.Reset(input)
+		expected := NewLexerWithOptions(input, opts)
+		expectedTokens := expected.Tokens()
+		tokens := l.Tokens()
+		if len(tokens) != len(expectedTokens) {
+			t.Fatalf("%q: expected %d tokens, got %d", input, len(expectedTokens), len(tokens))
+		}
+		for idx, tok := range tokens {
+			if tok != expectedTokens[idx] {
+				t.Errorf("%q: token %d: expected %+v, got %+v", input, idx, expectedTokens[idx], tok)
+			}
+		}
+		if len(l.Errors()) != len(expected.Errors()) {
+			t.Errorf("%q: expected errors %v, got %v", input, expected.Errors(), l.Errors())
+		}
+		if l.LineIndex().LineCount() != expected.LineIndex().LineCount() {
+			t.Errorf("%q: expected %d lines, got %d", input, expected.LineIndex().LineCount(), l.LineIndex().LineCount())
+		}
+
+		l.ResetReader(strings.NewReader(input))
+		tokens = l.Tokens()
+		if len(tokens) != len(expectedTokens) {
+			t.Fatalf("%q: expected %d streamed tokens, got %d", input, len(expectedTokens), len(tokens))
+		}
+		for idx, tok := range tokens {
+			if tok != expectedTokens[idx] {
+				t.Errorf("%q: streamed token %d: expected %+v, got %+v", input, idx, expectedTokens[idx], tok)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package lexer
+
+import "fmt"
+
+// Limits bounds the resources a lexer will spend on its input. Services
+// lexing untrusted input use them to reject inputs that would otherwise
+// exhaust memory or overflow the stack of a recursive parser. A zero
+// field means no limit.
+type Limits struct {
+	MaxFileSize          int // input size in bytes
+	MaxTokens            int // tokens produced, not counting EOF
+	MaxExpressionNesting int // depth of nested ( and [
+	MaxBlockDepth        int // depth of nested {
+}
+
+// DefaultLimits are generous enough for any hand-written program.
+var DefaultLimits = Limits{
+	MaxFileSize:          4 * 1024 * 1024,
+	MaxTokens:            1000000,
+	MaxExpressionNesting: 256,
+	MaxBlockDepth:        256,
+}
+
+// NewLexerWithLimits initializes a Lexer that enforces limits. Once a
+// limit is exceeded an error is recorded and lexing stops with EOF. It is
+// shorthand for NewLexerWithOptions with only Limits set.
+func NewLexerWithLimits(input string, limits Limits) *Lexer {
+	return NewLexerWithOptions(input, Options{Limits: limits})
+}
+
+// withinLimits updates the counters tracked for tok and reports whether
+// the lexer is still within its limits.
+func (l *Lexer) withinLimits(tok Token) bool {
+	if tok.Type == EOF {
+		return true
+	}
+
+	l.tokenCount++
+	if l.limits.MaxTokens > 0 && l.tokenCount > l.limits.MaxTokens {
+		l.addErrorAt(tok.Line, tok.Position, fmt.Sprintf("input has more than %d tokens", l.limits.MaxTokens))
+		return false
+	}
+
+	switch tok.Type {
+	case LPAREN, LBRACKET:
+		l.parenDepth++
+		if l.limits.MaxExpressionNesting > 0 && l.parenDepth > l.limits.MaxExpressionNesting {
+			l.addErrorAt(tok.Line, tok.Position, fmt.Sprintf("expression nesting exceeds the limit of %d", l.limits.MaxExpressionNesting))
+			return false
+		}
+	case RPAREN, RBRACKET:
+		if l.parenDepth > 0 {
+			l.parenDepth--
+		}
+	case LBRACE:
+		l.braceDepth++
+		if l.limits.MaxBlockDepth > 0 && l.braceDepth > l.limits.MaxBlockDepth {
+			l.addErrorAt(tok.Line, tok.Position, fmt.Sprintf("block nesting exceeds the limit of %d", l.limits.MaxBlockDepth))
+			return false
+		}
+	case RBRACE:
+		if l.braceDepth > 0 {
+			l.braceDepth--
+		}
+	}
+	return true
+}
+
+// documentWithinLimits reports whether the whole of doc, which was lexed
+// without limits, is within the lexer's limits and MaxErrors, so that
+// lexing it with them would not stop early.
+func (l *Lexer) documentWithinLimits(doc *document) bool {
+	if l.limits == (Limits{}) && l.maxErrors <= 0 {
+		return true
+	}
+	if l.limits.MaxFileSize > 0 && doc.text.size > l.limits.MaxFileSize {
+		return false
+	}
+
+	count, errors := 0, 0
+	parens, braces := 0, 0
+	maxParens, maxBraces := 0, 0
+	for _, chunk := range doc.tokens.chunks {
+		count += chunk.count
+		errors += chunk.errors
+		maxParens = max(maxParens, parens+chunk.parens.high, chunk.parens.rise)
+		parens = chunk.parens.net + max(parens, -chunk.parens.low)
+		maxBraces = max(maxBraces, braces+chunk.braces.high, chunk.braces.rise)
+		braces = chunk.braces.net + max(braces, -chunk.braces.low)
+	}
+	return (l.limits.MaxTokens <= 0 || count <= l.limits.MaxTokens) &&
+		(l.limits.MaxExpressionNesting <= 0 || maxParens <= l.limits.MaxExpressionNesting) &&
+		(l.limits.MaxBlockDepth <= 0 || maxBraces <= l.limits.MaxBlockDepth) &&
+		(l.maxErrors <= 0 || errors < l.maxErrors)
+}
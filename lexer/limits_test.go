@@ -0,0 +1,89 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLimitsFileSize(t *testing.T) {
+	l := NewLexerWithLimits("int x;", Limits{MaxFileSize: 5})
+
+	validateTokens([]ExpectedToken{{Type: "EOF", Literal: ""}}, l, t)
+	expectErrors(t, l, "[1:1] input is 6 bytes, exceeding the limit of 5 bytes")
+}
+
+func TestLimitsTokenCount(t *testing.T) {
+	l := NewLexerWithLimits("int x; int y;", Limits{MaxTokens: 4})
+
+	expected := []ExpectedToken{
+		{Type: "int", Literal: "int"},
+		{Type: "IDENT", Literal: "x"},
+		{Type: ";", Literal: ";"},
+		{Type: "int", Literal: "int"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, l, t)
+	expectErrors(t, l, "[1:12] input has more than 4 tokens")
+}
+
+func TestLimitsNesting(t *testing.T) {
+	limits := Limits{MaxExpressionNesting: 3, MaxBlockDepth: 2}
+
+	l := NewLexerWithLimits("x = ((a[b]));", limits)
+	validateTokens(expectedTokensFor("x = ((a[b]));"), l, t)
+	expectErrors(t, l)
+
+	l = NewLexerWithLimits("x = (((("+strings.Repeat("(", 1000), limits)
+	expected := []ExpectedToken{
+		{Type: "IDENT", Literal: "x"},
+		{Type: "=", Literal: "="},
+		{Type: "(", Literal: "("},
+		{Type: "(", Literal: "("},
+		{Type: "(", Literal: "("},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, l, t)
+	expectErrors(t, l, "[1:8] expression nesting exceeds the limit of 3")
+
+	l = NewLexerWithLimits("{ { } { } { {", limits)
+	expected = []ExpectedToken{
+		{Type: "{", Literal: "{"},
+		{Type: "{", Literal: "{"},
+		{Type: "}", Literal: "}"},
+		{Type: "{", Literal: "{"},
+		{Type: "}", Literal: "}"},
+		{Type: "{", Literal: "{"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, l, t)
+	expectErrors(t, l, "[1:13] block nesting exceeds the limit of 2")
+}
+
+func TestDefaultLimitsAcceptOrdinaryInput(t *testing.T) {
+	l := NewLexerWithLimits("int main() { return (1 + 2) * 3; }", DefaultLimits)
+	l.Tokens()
+	expectErrors(t, l)
+}
+
+// expectedTokensFor lexes input without limits and returns the
+// tokens in the form validateTokens expects.
+func expectedTokensFor(input string) []ExpectedToken {
+	result := []ExpectedToken{}
+	for _, tok := range NewLexer(input).Tokens() {
+		result = append(result, ExpectedToken{Type: tok.Type, Literal: tok.Literal})
+	}
+	return result
+}
+
+func expectErrors(t *testing.T, l *Lexer, expected ...string) {
+	t.Helper()
+	if len(l.Errors()) != len(expected) {
+		t.Errorf("expected %d errors, found %d: %v", len(expected), len(l.Errors()), l.Errors())
+		return
+	}
+	for idx, err := range l.Errors() {
+		if err.Error() != expected[idx] {
+			t.Errorf("error expected '%s', got '%s'", expected[idx], err.Error())
+		}
+	}
+}
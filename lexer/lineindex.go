@@ -0,0 +1,147 @@
+package lexer
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// LineIndex maps between byte offsets in an input and line/column
+// positions. Lines and columns start at 1 and columns count runes, as in
+// Token. The UTF-16 variants count UTF-16 code units instead, which is
+// what the Language Server Protocol uses.
+type LineIndex struct {
+	input  string
+	starts []int // offset of the first byte of each line
+}
+
+// NewLineIndex builds the index for input. "\n", "\r\n", and "\r" all end
+// a line and a leading byte order mark is skipped, as in the lexer.
+func NewLineIndex(input string) *LineIndex {
+	first := 0
+	if strings.HasPrefix(input, utf8BOM) {
+		first = len(utf8BOM)
+	}
+	idx := &LineIndex{input: input, starts: []int{first}}
+	for i := first; i < len(input); i++ {
+		switch input[i] {
+		case '\r':
+			if i+1 < len(input) && input[i+1] == '\n' {
+				i++
+			}
+			idx.add(i + 1)
+		case '\n':
+			idx.add(i + 1)
+		}
+	}
+	return idx
+}
+
+// LineIndex returns the index of the lines the lexer has reached. Once
+// the lexer has returned EOF it covers the whole input.
+func (l *Lexer) LineIndex() *LineIndex {
+	return l.lines
+}
+
+func (idx *LineIndex) add(start int) {
+	if start > idx.starts[len(idx.starts)-1] {
+		idx.starts = append(idx.starts, start)
+	}
+}
+
+// LineCount returns the number of lines in the index.
+func (idx *LineIndex) LineCount() int {
+	return len(idx.starts)
+}
+
+// LineStart returns the offset of the first byte of line, or false if
+// the line is not in the index.
+func (idx *LineIndex) LineStart(line int) (int, bool) {
+	if line < 1 || line > len(idx.starts) {
+		return 0, false
+	}
+	return idx.starts[line-1], true
+}
+
+// PositionFor returns the line and column of offset.
+func (idx *LineIndex) PositionFor(offset int) (int, int) {
+	return idx.positionFor(offset, runeLen)
+}
+
+// OffsetFor returns the offset of the char at line and column, or false
+// if the position is outside the input.
+func (idx *LineIndex) OffsetFor(line int, column int) (int, bool) {
+	return idx.offsetFor(line, column, runeLen)
+}
+
+// UTF16PositionFor returns the line and UTF-16 column of offset.
+func (idx *LineIndex) UTF16PositionFor(offset int) (int, int) {
+	return idx.positionFor(offset, utf16Len)
+}
+
+// OffsetForUTF16 returns the offset of the char at line and UTF-16
+// column, or false if the position is outside the input.
+func (idx *LineIndex) OffsetForUTF16(line int, column int) (int, bool) {
+	return idx.offsetFor(line, column, utf16Len)
+}
+
+// positionFor returns the line of offset and its column, counting each
+// rune before it on the line as width(r) columns.
+func (idx *LineIndex) positionFor(offset int, width func(rune) int) (int, int) {
+	line := sort.Search(len(idx.starts), func(i int) bool { return idx.starts[i] > offset })
+	if line == 0 {
+		return 1, 1
+	}
+	column := 1
+	for _, r := range idx.input[idx.starts[line-1]:min(offset, len(idx.input))] {
+		column += width(r)
+	}
+	return line, column
+}
+
+// offsetFor returns the offset of the char at line and column, counting
+// each rune as width(r) columns.
+func (idx *LineIndex) offsetFor(line int, column int, width func(rune) int) (int, bool) {
+	start, ok := idx.LineStart(line)
+	if !ok || column < 1 {
+		return 0, false
+	}
+	end := idx.lineEnd(line)
+	offset, units := start, 1
+	for units < column {
+		if offset >= end {
+			return 0, false
+		}
+		r, size := utf8.DecodeRuneInString(idx.input[offset:])
+		offset += size
+		units += width(r)
+	}
+	return offset, true
+}
+
+// lineEnd returns the offset just past the last char of line, which is
+// the offset of its line ending.
+func (idx *LineIndex) lineEnd(line int) int {
+	end := len(idx.input)
+	if line < len(idx.starts) {
+		end = idx.starts[line]
+		if end > 0 && idx.input[end-1] == '\n' {
+			end--
+		}
+		if end > 0 && idx.input[end-1] == '\r' {
+			end--
+		}
+	}
+	return end
+}
+
+func runeLen(r rune) int {
+	return 1
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
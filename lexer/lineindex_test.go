@@ -0,0 +1,101 @@
+package lexer
+
+import (
+	"testing"
+
+	"github.com/hculpan/htc/examples"
+)
+
+func TestLineIndexMatchesLexer(t *testing.T) {
+	inputs := []string{
+		"int a;\r\nint b;\rint c;\n/* x\ny */",
+		"\xef\xbb\xbfint a;\n",
+		"",
+	}
+	for _, ex := range examples.All() {
+		inputs = append(inputs, ex.Source)
+	}
+
+	for _, input := range inputs {
+		l := NewLexer(input)
+		l.Tokens()
+		lexed, built := l.LineIndex(), NewLineIndex(input)
+		if lexed.LineCount() != built.LineCount() {
+			t.Errorf("%q: expected %d lines, got %d", input, built.LineCount(), lexed.LineCount())
+			continue
+		}
+		for line := 1; line <= built.LineCount(); line++ {
+			expected, _ := built.LineStart(line)
+			if start, _ := lexed.LineStart(line); start != expected {
+				t.Errorf("%q: line %d expected start %d, got %d", input, line, expected, start)
+			}
+		}
+	}
+}
+
+func TestLineIndexTokenPositions(t *testing.T) {
+	for _, ex := range examples.All() {
+		l := NewLexer(ex.Source)
+		idx := NewLineIndex(ex.Source)
+		for _, tok := range l.Tokens() {
+			offset, ok := idx.OffsetFor(tok.Line, tok.Position)
+			if !ok {
+				t.Errorf("%s: no offset for %d:%d", ex.Name, tok.Line, tok.Position)
+				continue
+			}
+			if line, column := idx.PositionFor(offset); line != tok.Line || column != tok.Position {
+				t.Errorf("%s: offset %d maps to %d:%d, expected %d:%d", ex.Name, offset, line, column, tok.Line, tok.Position)
+			}
+		}
+	}
+}
+
+func TestLineIndexOffsets(t *testing.T) {
+	idx := NewLineIndex("ab\r\ncd\n\nef")
+
+	positions := []struct {
+		offset int
+		line   int
+		column int
+	}{
+		{0, 1, 1}, {1, 1, 2}, {2, 1, 3}, {4, 2, 1}, {5, 2, 2}, {7, 3, 1}, {8, 4, 1}, {9, 4, 2}, {10, 4, 3},
+	}
+	for _, p := range positions {
+		if line, column := idx.PositionFor(p.offset); line != p.line || column != p.column {
+			t.Errorf("offset %d: expected %d:%d, got %d:%d", p.offset, p.line, p.column, line, column)
+		}
+		if offset, ok := idx.OffsetFor(p.line, p.column); !ok || offset != p.offset {
+			t.Errorf("%d:%d: expected offset %d, got %d (%v)", p.line, p.column, p.offset, offset, ok)
+		}
+	}
+
+	invalid := [][2]int{{0, 1}, {5, 1}, {1, 0}, {1, 4}, {3, 2}, {4, 4}}
+	for _, p := range invalid {
+		if _, ok := idx.OffsetFor(p[0], p[1]); ok {
+			t.Errorf("%d:%d: expected no offset", p[0], p[1])
+		}
+	}
+}
+
+func TestLineIndexUTF16(t *testing.T) {
+	// é is 2 bytes and 1 UTF-16 unit, 😀 is 4 bytes and 2 UTF-16 units
+	idx := NewLineIndex("x\né😀y")
+
+	positions := []struct {
+		offset int
+		column int
+	}{
+		{2, 1}, {4, 2}, {8, 4}, {9, 5},
+	}
+	for _, p := range positions {
+		if line, column := idx.UTF16PositionFor(p.offset); line != 2 || column != p.column {
+			t.Errorf("offset %d: expected 2:%d, got %d:%d", p.offset, p.column, line, column)
+		}
+		if offset, ok := idx.OffsetForUTF16(2, p.column); !ok || offset != p.offset {
+			t.Errorf("2:%d: expected offset %d, got %d (%v)", p.column, p.offset, offset, ok)
+		}
+	}
+	if _, ok := idx.OffsetForUTF16(2, 6); ok {
+		t.Error("expected no offset past the end of the line")
+	}
+}
@@ -0,0 +1,112 @@
+package lexer
+
+import (
+	"strings"
+)
+
+// LineEnding identifies the line ending style of the input.
+type LineEnding int
+
+const (
+	LF   LineEnding = iota // "\n"
+	CRLF                   // "\r\n"
+	CR                     // "\r"
+)
+
+const utf8BOM = "\xef\xbb\xbf"
+
+// String returns the characters making up the line ending.
+func (e LineEnding) String() string {
+	switch e {
+	case CRLF:
+		return "\r\n"
+	case CR:
+		return "\r"
+	default:
+		return "\n"
+	}
+}
+
+// LineEnding returns the line ending style of the input, taken from its
+// first line ending. Input without line endings reports LF. A lexer
+// reading from an io.Reader learns the style when it reaches the first
+// line ending.
+func (l *Lexer) LineEnding() LineEnding {
+	return l.lineEnding
+}
+
+// normalize prepares the input before lexing starts. A UTF-8 byte order
+// mark is skipped. The input itself is left untouched so positions refer
+// to the original text; the lexer treats "\n", "\r\n", and "\r" alike
+// when counting lines. UTF-16 input is rejected since lexing it byte by
+// byte only produces noise.
+func (l *Lexer) normalize() {
+	if isUTF16(l.input) {
+		l.errors = append(l.errors, &Error{File: l.filename, Msg: "input appears to be UTF-16 encoded; save the file as UTF-8"})
+		l.input = ""
+		l.reader = nil
+		return
+	}
+
+	if strings.HasPrefix(l.input, utf8BOM) {
+		l.readPosition = len(utf8BOM)
+		l.lineStart = len(utf8BOM)
+	}
+}
+
+// noteLineEnding records the line ending style if the current char
+// starts the first line ending seen.
+func (l *Lexer) noteLineEnding() {
+	if l.lineEndingSet {
+		return
+	}
+	switch {
+	case l.ch == '\n':
+		l.lineEnding = LF
+	case l.peekChar() == '\n':
+		l.lineEnding = CRLF
+	default:
+		l.lineEnding = CR
+	}
+	l.lineEndingSet = true
+}
+
+// utf16Prefix is how many leading bytes isUTF16 inspects.
+const utf16Prefix = 64
+
+// isUTF16 reports whether input starts with a UTF-16 byte order mark or
+// looks like ASCII text encoded as UTF-16: its first utf16Prefix bytes,
+// at least two chars' worth, pair up with a NUL on the same side of
+// every pair. A stray NUL in UTF-8 text does not match, and is lexed as
+// ILLEGAL instead.
+func isUTF16(input string) bool {
+	if strings.HasPrefix(input, "\xfe\xff") || strings.HasPrefix(input, "\xff\xfe") {
+		return true
+	}
+	prefix := input[:min(len(input), utf16Prefix)]
+	if len(prefix) < 4 {
+		return false
+	}
+	littleEndian, bigEndian := true, true
+	for i := 0; i+1 < len(prefix); i += 2 {
+		littleEndian = littleEndian && prefix[i] != 0 && prefix[i+1] == 0
+		bigEndian = bigEndian && prefix[i] == 0 && prefix[i+1] != 0
+	}
+	return littleEndian || bigEndian
+}
+
+// detectLineEnding returns the style of the first line ending in input
+// and whether input has one.
+func detectLineEnding(input string) (LineEnding, bool) {
+	idx := strings.IndexAny(input, "\r\n")
+	if idx < 0 {
+		return LF, false
+	}
+	if input[idx] == '\n' {
+		return LF, true
+	}
+	if idx+1 < len(input) && input[idx+1] == '\n' {
+		return CRLF, true
+	}
+	return CR, true
+}
@@ -0,0 +1,108 @@
+package lexer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLineEndings(t *testing.T) {
+	inputs := map[string]LineEnding{
+		"int a;\nint b;\r\n// c\rd":   LF,
+		"int a;\r\nint b;\r\n// c\rd": CRLF,
+		"int a;\rint b;\r\n// c\nd":   CR,
+	}
+
+	for input, ending := range inputs {
+		expected := []Token{
+			{Type: INT_TYPE, Literal: "int", Line: 1, Position: 1, EndLine: 1, EndColumn: 4},
+			{Type: IDENT, Literal: "a", Line: 1, Position: 5, EndLine: 1, EndColumn: 6},
+			{Type: SEMICOLON, Literal: ";", Line: 1, Position: 6, EndLine: 1, EndColumn: 7},
+			{Type: INT_TYPE, Literal: "int", Line: 2, Position: 1, EndLine: 2, EndColumn: 4},
+			{Type: IDENT, Literal: "b", Line: 2, Position: 5, EndLine: 2, EndColumn: 6},
+			{Type: SEMICOLON, Literal: ";", Line: 2, Position: 6, EndLine: 2, EndColumn: 7},
+			{Type: COMMENT, Literal: "// c", Line: 3, Position: 1, EndLine: 3, EndColumn: 5},
+			{Type: IDENT, Literal: "d", Line: 4, Position: 1, EndLine: 4, EndColumn: 2},
+			{Type: EOF, Literal: "", Line: 4, Position: 2, EndLine: 4, EndColumn: 2},
+		}
+
+		l := NewLexer(input)
+		if l.LineEnding() != ending {
+			t.Errorf("%q: expected line ending %q, got %q", input, ending, l.LineEnding())
+		}
+		tokens := l.Tokens()
+		if len(tokens) != len(expected) {
+			t.Errorf("%q: expected %d tokens, got %d tokens", input, len(expected), len(tokens))
+			continue
+		}
+		for idx, tok := range tokens {
+			// offsets depend on the line endings, so check them by slicing
+			if text := input[tok.StartOffset:tok.EndOffset]; text != tok.Literal {
+				t.Errorf("%q: offsets of %+v give %q", input, tok, text)
+			}
+			tok.StartOffset, tok.EndOffset = 0, 0
+			if tok != expected[idx] {
+				t.Errorf("%q: expected %+v, got %+v", input, expected[idx], tok)
+			}
+		}
+	}
+}
+
+func TestNoLineEnding(t *testing.T) {
+	if ending := NewLexer("int a;").LineEnding(); ending != LF {
+		t.Errorf("expected line ending %q, got %q", LF, ending)
+	}
+}
+
+func TestLineEndingsInBlockComment(t *testing.T) {
+	tokens := NewLexer("/* a\r\nb\rc\n*/ x").Tokens()
+	if tokens[1].Line != 4 || tokens[1].Position != 4 {
+		t.Errorf("expected x at 4:4, got %d:%d", tokens[1].Line, tokens[1].Position)
+	}
+}
+
+func TestByteOrderMark(t *testing.T) {
+	l := NewLexer("\xef\xbb\xbfint x;")
+	expected := []ExpectedToken{
+		{Type: "int", Literal: "int"},
+		{Type: "IDENT", Literal: "x"},
+		{Type: ";", Literal: ";"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, l, t)
+
+	tokens := NewLexer("\xef\xbb\xbfint x;").Tokens()
+	if tokens[0].Position != 1 {
+		t.Errorf("expected int at column 1, got %d", tokens[0].Position)
+	}
+}
+
+func TestRejectUTF16(t *testing.T) {
+	inputs := []string{
+		"\xff\xfei\x00n\x00t\x00",
+		"\xfe\xff\x00i\x00n\x00t",
+		"i\x00n\x00t\x00",
+	}
+
+	for _, input := range inputs {
+		l := NewLexer(input)
+		validateTokens([]ExpectedToken{{Type: "EOF", Literal: ""}}, l, t)
+		if len(l.Errors()) != 1 {
+			t.Errorf("%q: expected 1 error, found %d", input, len(l.Errors()))
+		} else if l.Errors()[0].Error() != "input appears to be UTF-16 encoded; save the file as UTF-8" {
+			t.Errorf("%q: unexpected error '%s'", input, l.Errors()[0].Error())
+		}
+	}
+}
+
+func TestNULIsNotUTF16(t *testing.T) {
+	for _, input := range []string{"a\x00b", "\x00", "\x00a", "i\x00n\x00t\x00x;"} {
+		l := NewLexer(input)
+		tokens := l.Tokens()
+		if len(l.Errors()) != 0 {
+			t.Errorf("%q: expected no errors, got %v", input, l.Errors())
+		}
+		if !slices.ContainsFunc(tokens, func(tok Token) bool { return tok.Type == ILLEGAL }) {
+			t.Errorf("%q: expected the NUL as ILLEGAL, got %+v", input, tokens)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var baseNames = map[int]string{
+	2:  "binary",
+	8:  "octal",
+	10: "decimal",
+	16: "hexadecimal",
+}
+
+// intSuffixes are the suffixes an integer literal may end with, longest
+// first. U marks the literal unsigned and L or LL long or long long, in
+// either order; the two Ls of LL must have the same case.
+var intSuffixes = []string{
+	"ull", "uLL", "Ull", "ULL", "llu", "llU", "LLu", "LLU",
+	"ul", "uL", "Ul", "UL", "lu", "lU", "Lu", "LU", "ll", "LL",
+	"u", "U", "l", "L",
+}
+
+// readNumber reads an integer literal starting with a digit and returns
+// it along with its base and suffix. Literals starting with 0x or 0X are
+// hexadecimal, 0b or 0B binary, and any other literal with a leading 0
+// octal. The whole run of letters and digits is consumed so that a
+// malformed literal like 0b12 is reported as one token.
+func (l *Lexer) readNumber() (string, int, string, error) {
+	position := l.position
+	for isDigit(l.ch) || isLetter(l.ch) {
+		l.readChar()
+	}
+	literal := l.slice(position, l.position)
+	suffix := intSuffix(literal)
+	number := literal[:len(literal)-len(suffix)]
+
+	base, digits := 10, number
+	if len(number) >= 2 && number[0] == '0' {
+		switch number[1] {
+		case 'x', 'X':
+			base, digits = 16, number[2:]
+		case 'b', 'B':
+			base, digits = 2, number[2:]
+		default:
+			base, digits = 8, number[1:]
+		}
+	}
+
+	if digits == "" {
+		return literal, base, suffix, fmt.Errorf("%s literal has no digits", baseNames[base])
+	}
+	for i := 0; i < len(digits); i++ {
+		if digitValue(digits[i]) >= base {
+			return literal, base, suffix, fmt.Errorf("invalid digit '%c' in %s literal", digits[i], baseNames[base])
+		}
+	}
+	return literal, base, suffix, nil
+}
+
+// intSuffix returns the integer suffix literal ends with, or "" if it
+// has none. The leading digit is never part of the suffix.
+func intSuffix(literal string) string {
+	for _, suffix := range intSuffixes {
+		if len(literal) > len(suffix) && strings.HasSuffix(literal, suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// digitValue returns the value of ch as a digit, or 36 if ch is not a
+// digit in any supported base.
+func digitValue(ch byte) int {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'z':
+		return int(ch-'a') + 10
+	case 'A' <= ch && ch <= 'Z':
+		return int(ch-'A') + 10
+	}
+	return 36
+}
+
+// IntValue returns the value of an INT token, taking its base and
+// suffix into account.
+func (t Token) IntValue() (int64, error) {
+	if t.Type != INT {
+		return 0, fmt.Errorf("%s token has no integer value", t.Type)
+	}
+	digits := strings.TrimSuffix(t.Literal, t.Suffix)
+	if t.Base == 16 || t.Base == 2 {
+		digits = digits[2:]
+	}
+	return strconv.ParseInt(digits, t.Base, 64)
+}
@@ -0,0 +1,79 @@
+package lexer
+
+import (
+	"testing"
+)
+
+func TestIntegerLiterals(t *testing.T) {
+	inputs := []struct {
+		input  string
+		base   int
+		suffix string
+		value  int64
+	}{
+		{"0", 10, "", 0},
+		{"42", 10, "", 42},
+		{"0x1F", 16, "", 31},
+		{"0XfF", 16, "", 255},
+		{"0755", 8, "", 493},
+		{"00", 8, "", 0},
+		{"0b1010", 2, "", 10},
+		{"0B1", 2, "", 1},
+		{"42u", 10, "u", 42},
+		{"0U", 10, "U", 0},
+		{"7L", 10, "L", 7},
+		{"0x1Ful", 16, "ul", 31},
+		{"0755LU", 8, "LU", 493},
+		{"0b11ll", 2, "ll", 3},
+		{"9uLL", 10, "uLL", 9},
+		{"9LLu", 10, "LLu", 9},
+	}
+
+	for _, in := range inputs {
+		l := NewLexer(in.input)
+		tokens := l.Tokens()
+		if len(tokens) != 2 {
+			t.Errorf("%s: expected 2 tokens, got %d", in.input, len(tokens))
+			continue
+		}
+		tok := tokens[0]
+		if tok.Type != INT || tok.Literal != in.input || tok.Base != in.base || tok.Suffix != in.suffix {
+			t.Errorf("%s: expected INT in base %d with suffix %q, got %+v", in.input, in.base, in.suffix, tok)
+		}
+		if value, err := tok.IntValue(); err != nil || value != in.value {
+			t.Errorf("%s: expected value %d, got %d (%v)", in.input, in.value, value, err)
+		}
+		if l.HasErrors() {
+			t.Errorf("%s: unexpected errors %v", in.input, l.Errors())
+		}
+	}
+}
+
+func TestMalformedIntegerLiterals(t *testing.T) {
+	inputs := map[string]string{
+		"x = 0x;":    "[1:5] hexadecimal literal has no digits",
+		"x = 0b;":    "[1:5] binary literal has no digits",
+		"x = 0b102;": "[1:5] invalid digit '2' in binary literal",
+		"x = 09;":    "[1:5] invalid digit '9' in octal literal",
+		"x = 0x1G;":  "[1:5] invalid digit 'G' in hexadecimal literal",
+		"x = 2foo;":  "[1:5] invalid digit 'f' in decimal literal",
+		"x = 1lL;":   "[1:5] invalid digit 'l' in decimal literal",
+		"x = 1uu;":   "[1:5] invalid digit 'u' in decimal literal",
+		"x = 0xu;":   "[1:5] hexadecimal literal has no digits",
+	}
+
+	for input, msg := range inputs {
+		l := NewLexer(input)
+		tokens := l.Tokens()
+		if len(tokens) != 5 || tokens[2].Type != INT {
+			t.Errorf("%s: expected the literal as a single INT token, got %v", input, tokens)
+		}
+		expectErrors(t, l, msg)
+	}
+}
+
+func TestIntValueOfOtherTokens(t *testing.T) {
+	if _, err := (Token{Type: IDENT, Literal: "x"}).IntValue(); err == nil {
+		t.Error("expected error for IDENT token")
+	}
+}
@@ -0,0 +1,61 @@
+package lexer
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// operatorState is a state in the operator trie. Leaving the state on
+// edges[i] moves to operatorStates[targets[i]]. A state with a tokenType
+// ends a complete operator.
+type operatorState struct {
+	tokenType TokenType
+	edges     string
+	targets   []int
+}
+
+// readOperator reads the longest operator starting at the current char.
+// If no operator matches, the current char is returned as ILLEGAL, or the
+// whole rune for a non-ASCII char. Like the other single-char cases in
+// nextToken, it leaves the lexer on the last char of the token.
+func (l *Lexer) readOperator(line int, column int) Token {
+	state, length := 0, 0
+	var tokenType TokenType
+	for i := 0; ; i++ {
+		ch := l.peekCharN(i)
+		idx := strings.IndexByte(operatorStates[state].edges, ch)
+		if ch == 0 || idx < 0 {
+			break
+		}
+		state = operatorStates[state].targets[idx]
+		if operatorStates[state].tokenType != "" {
+			tokenType = operatorStates[state].tokenType
+			length = i + 1
+		}
+	}
+
+	if length == 0 && l.ch >= utf8.RuneSelf {
+		_, size := l.peekRune()
+		literal := l.slice(l.position, l.position+size)
+		for i := 1; i < size; i++ {
+			l.readChar()
+		}
+		return Token{Type: ILLEGAL, Literal: literal, Line: line, Position: column}
+	} else if length == 0 {
+		return newToken(ILLEGAL, l.ch, line, column)
+	}
+	literal := l.slice(l.position, l.position+length)
+	for i := 1; i < length; i++ {
+		l.readChar()
+	}
+	return Token{Type: tokenType, Literal: literal, Line: line, Position: column}
+}
+
+// peekCharN returns the char n positions after the current one without
+// advancing. peekCharN(0) is the current char.
+func (l *Lexer) peekCharN(n int) byte {
+	if !l.ensure(l.position + n) {
+		return 0
+	}
+	return l.input[l.position+n-l.base]
+}
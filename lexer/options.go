@@ -0,0 +1,100 @@
+package lexer
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// Options configures a Lexer. The zero value lexes the way NewLexer
+// does.
+type Options struct {
+	Filename     string // recorded in tokens and diagnostics when set
+	TabWidth     int    // a tab advances to the next multiple of TabWidth columns; 0 counts it as one column
+	SkipComments bool   // see SetSkipComments
+	Trivia       bool   // see SetTrivia
+	MaxErrors    int    // lexing stops after this many errors; 0 means no limit
+	Limits       Limits
+	KeywordHook  KeywordHook
+
+	// StringRecovery decides where lexing resumes after a string literal
+	// missing its closing quote.
+	StringRecovery StringRecovery
+}
+
+// StringRecovery is a policy for continuing after an unterminated string
+// literal. Under every policy the literal is reported as an error and
+// its token has Unterminated set.
+type StringRecovery int
+
+const (
+	// RecoverAtNewline ends the literal at its line ending, which suits
+	// strings that were simply left open.
+	RecoverAtNewline StringRecovery = iota
+	// RecoverAtQuote continues the literal across lines to the next
+	// quote, which suits literals broken by a stray line ending.
+	RecoverAtQuote
+	// StopAtUnterminated ends the input after the literal, for tools that
+	// distrust everything after the first unterminated string.
+	StopAtUnterminated
+)
+
+// NewLexerWithOptions initializes a Lexer configured by opts.
+//
+// TabWidth applies to the columns in tokens and diagnostics. LineIndex
+// columns always count a tab as one column.
+func NewLexerWithOptions(input string, opts Options) *Lexer {
+	l := &Lexer{}
+	l.reset(input, opts)
+	return l
+}
+
+// NewLexerFromReaderWithOptions initializes a streaming Lexer, as
+// NewLexerFromReader does, configured by opts. Input beyond
+// Limits.MaxFileSize is reported once it is read and ends the input.
+func NewLexerFromReaderWithOptions(r io.Reader, opts Options) *Lexer {
+	l := &Lexer{}
+	l.resetReader(r, opts)
+	return l
+}
+
+// apply configures l from opts before any token is lexed.
+func (l *Lexer) apply(opts Options) {
+	l.filename = opts.Filename
+	l.tabWidth = opts.TabWidth
+	l.skipComments = opts.SkipComments
+	l.SetTrivia(opts.Trivia)
+	l.maxErrors = opts.MaxErrors
+	l.limits = opts.Limits
+	l.keywordHook = opts.KeywordHook
+	l.stringRecovery = opts.StringRecovery
+}
+
+// options returns the options l was configured with.
+func (l *Lexer) options() Options {
+	return Options{
+		Filename:     l.filename,
+		TabWidth:     l.tabWidth,
+		SkipComments: l.skipComments,
+		Trivia:       l.trivia,
+		MaxErrors:    l.maxErrors,
+		Limits:       l.limits,
+		KeywordHook:  l.keywordHook,
+
+		StringRecovery: l.stringRecovery,
+	}
+}
+
+// advanceColumn returns the column after text when it starts at column.
+func (l *Lexer) advanceColumn(column int, text string) int {
+	if l.tabWidth <= 0 {
+		return column + utf8.RuneCountInString(text)
+	}
+	for _, r := range text {
+		if r == '\t' {
+			column += l.tabWidth - (column-1)%l.tabWidth
+		} else {
+			column++
+		}
+	}
+	return column
+}
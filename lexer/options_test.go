@@ -0,0 +1,150 @@
+package lexer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOptionsTabWidth(t *testing.T) {
+	input := "\tx =\t1;\n  \t\"a\n"
+	l := NewLexerWithOptions(input, Options{TabWidth: 4})
+
+	expected := []Token{
+		{Type: IDENT, Literal: "x", Line: 1, Position: 5, EndLine: 1, EndColumn: 6, StartOffset: 1, EndOffset: 2},
+		{Type: ASSIGN, Literal: "=", Line: 1, Position: 7, EndLine: 1, EndColumn: 8, StartOffset: 3, EndOffset: 4},
+		{Type: INT, Literal: "1", Line: 1, Position: 9, EndLine: 1, EndColumn: 10, StartOffset: 5, EndOffset: 6, Base: 10},
+		{Type: SEMICOLON, Literal: ";", Line: 1, Position: 10, EndLine: 1, EndColumn: 11, StartOffset: 6, EndOffset: 7},
+		{Type: STRING, Literal: "a", Line: 2, Position: 5, EndLine: 2, EndColumn: 7, StartOffset: 11, EndOffset: 13, Unterminated: true},
+	}
+	for idx, tok := range expected {
+		if got := l.NextToken(); got != tok {
+			t.Errorf("token %d: expected %+v, got %+v", idx, tok, got)
+		}
+	}
+}
+
+func TestOptionsFilename(t *testing.T) {
+	l := NewLexerWithOptions("x = \"a\n", Options{Filename: "main.c"})
+	l.Tokens()
+	expectErrors(t, l, "main.c:1:5: non-terminated string")
+}
+
+func TestOptionsMaxErrors(t *testing.T) {
+	l := NewLexerWithOptions("\"a\n\"b\n\"c\nx", Options{MaxErrors: 2})
+
+	expected := []ExpectedToken{
+		{Type: "STRING", Literal: "a"},
+		{Type: "STRING", Literal: "b"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, l, t)
+	expectErrors(t, l,
+		"[1:1] non-terminated string",
+		"[2:1] non-terminated string",
+		"[2:1] too many errors")
+}
+
+func TestOptionsSkipCommentsAndTrivia(t *testing.T) {
+	input := "a // one\nb /* two */ c"
+
+	l := NewLexerWithOptions(input, Options{SkipComments: true})
+	validateTokens(expectedTokensFor("a b c"), l, t)
+
+	l = NewLexerWithOptions(input, Options{Trivia: true})
+	var sb strings.Builder
+	for _, tok := range l.Tokens() {
+		sb.WriteString(tok.LeadingTrivia + tok.Literal + tok.TrailingTrivia)
+	}
+	if sb.String() != input {
+		t.Errorf("expected trivia to cover %q, got %q", input, sb.String())
+	}
+}
+
+func TestOptionsFromReader(t *testing.T) {
+	input := "int\tx; // c\nx = 1;\n"
+	opts := Options{TabWidth: 8, SkipComments: true, KeywordHook: KeywordsAsIdentifiers(INT_TYPE)}
+
+	expected := NewLexerWithOptions(input, opts).Tokens()
+	tokens := NewLexerFromReaderWithOptions(strings.NewReader(input), opts).Tokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for idx, tok := range tokens {
+		if tok != expected[idx] {
+			t.Errorf("token %d: expected %+v, got %+v", idx, expected[idx], tok)
+		}
+	}
+}
+
+func TestOptionsReaderFileSize(t *testing.T) {
+	l := NewLexerFromReaderWithOptions(strings.NewReader(strings.Repeat("x ", 5000)), Options{Limits: Limits{MaxFileSize: 4100}})
+	tokens := l.Tokens()
+	if len(tokens) != 2051 {
+		t.Errorf("expected 2051 tokens, got %d", len(tokens))
+	}
+	expectErrors(t, l, "[1:4101] input exceeds the limit of 4100 bytes")
+}
+
+func TestUpdateWithTabWidth(t *testing.T) {
+	opts := Options{TabWidth: 4}
+	l := NewLexerWithOptions("a\tb\tc\nd\te", opts)
+	l.Update(Range{Start: 0, End: 0}, "xy")
+	tokens := l.DocumentTokens()
+
+	expected := NewLexerWithOptions("xya\tb\tc\nd\te", opts).Tokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for idx, tok := range tokens {
+		if tok != expected[idx] {
+			t.Errorf("token %d: expected %+v, got %+v", idx, expected[idx], tok)
+		}
+	}
+}
+
+func TestUpdateWithTabWidthAddsLine(t *testing.T) {
+	l := NewLexerWithOptions(";;\t", Options{TabWidth: 4})
+	l.Update(Range{Start: 1, End: 1}, "\r")
+	tokens := l.DocumentTokens()
+
+	eof := tokens[len(tokens)-1]
+	if eof.Line != 2 || eof.Position != 5 {
+		t.Errorf("expected EOF at 2:5, got %d:%d", eof.Line, eof.Position)
+	}
+}
+
+func TestOptionsFilenameInTokens(t *testing.T) {
+	l := NewLexerWithOptions("x\n\"a", Options{Filename: "src/main.c"})
+	for _, tok := range l.Tokens() {
+		if tok.File != "src/main.c" {
+			t.Errorf("expected %+v to be in src/main.c", tok)
+		}
+	}
+
+	var lexErr *Error
+	if !errors.As(l.Errors()[0], &lexErr) {
+		t.Fatalf("expected an *Error, got %T", l.Errors()[0])
+	}
+	expected := Error{File: "src/main.c", Line: 2, Column: 1, Msg: "non-terminated string"}
+	if *lexErr != expected {
+		t.Errorf("expected %+v, got %+v", expected, *lexErr)
+	}
+}
+
+func TestErrorFormat(t *testing.T) {
+	tests := []struct {
+		err      Error
+		expected string
+	}{
+		{Error{File: "main.c", Line: 4, Column: 13, Msg: "non-terminated string"}, "main.c:4:13: non-terminated string"},
+		{Error{Line: 4, Column: 13, Msg: "non-terminated string"}, "[4:13] non-terminated string"},
+		{Error{File: "main.c", Msg: "input appears to be UTF-16 encoded"}, "main.c: input appears to be UTF-16 encoded"},
+		{Error{Msg: "input appears to be UTF-16 encoded"}, "input appears to be UTF-16 encoded"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Print writes source text for tokens to w. Each token is placed at its
+// recorded Line and Position, so lexing the output yields the same
+// tokens as the original input. Whitespace between tokens is written as
+// spaces and newlines.
+//
+// Tokens lexed in trivia mode are instead written as their source text
+// between their trivia, so the output matches the original input byte
+// for byte, comments and string escapes included. Only a leading byte
+// order mark is left out.
+func Print(w io.Writer, tokens []Token) error {
+	if slices.ContainsFunc(tokens, hasTrivia) {
+		for _, tok := range tokens {
+			if _, err := io.WriteString(w, tok.LeadingTrivia+tok.Raw+tok.TrailingTrivia); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	line, column := 1, 1
+	for _, tok := range tokens {
+		for line < tok.Line {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+			line++
+			column = 1
+		}
+		if tok.Position > column {
+			if _, err := io.WriteString(w, strings.Repeat(" ", tok.Position-column)); err != nil {
+				return err
+			}
+			column = tok.Position
+		} else if tok.Position < column && column > 1 {
+			// tokens without usable positions still need separating
+			if _, err := io.WriteString(w, " "); err != nil {
+				return err
+			}
+			column++
+		}
+
+		text := tokenText(tok)
+		if _, err := io.WriteString(w, text); err != nil {
+			return err
+		}
+		if count, end := lineEndings(text); count > 0 {
+			line += count
+			column = utf8.RuneCountInString(text[end:]) + 1
+		} else {
+			column += utf8.RuneCountInString(text)
+		}
+	}
+	return nil
+}
+
+// Reconstruct returns the source text for tokens as produced by Print.
+func Reconstruct(tokens []Token) string {
+	var sb strings.Builder
+	Print(&sb, tokens)
+	return sb.String()
+}
+
+// lineEndings returns the number of line endings in text and the offset
+// just past the last one. As in the lexer, "\n", "\r\n", and "\r" each
+// end a line.
+func lineEndings(text string) (count, end int) {
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\r':
+			if i+1 < len(text) && text[i+1] == '\n' {
+				i++
+			}
+			count, end = count+1, i+1
+		case '\n':
+			count, end = count+1, i+1
+		}
+	}
+	return count, end
+}
+
+// hasTrivia reports whether tok was lexed in trivia mode.
+func hasTrivia(tok Token) bool {
+	return tok.Raw != "" || tok.LeadingTrivia != "" || tok.TrailingTrivia != ""
+}
+
+// tokenText returns the source form of tok.
+func tokenText(tok Token) string {
+	switch tok.Type {
+	case STRING:
+		return quoteString(tok.Literal)
+	default:
+		return tok.Literal
+	}
+}
+
+// quoteString returns value as a string literal, escaping the chars the
+// lexer decodes.
+func quoteString(value string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		switch ch {
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case 0:
+			sb.WriteString(`\0`)
+		case '\\', '"':
+			sb.WriteByte('\\')
+			sb.WriteByte(ch)
+		default:
+			if ch < 0x20 || ch == 0x7f {
+				fmt.Fprintf(&sb, `\x%02x`, ch)
+			} else {
+				sb.WriteByte(ch)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,88 @@
+package lexer
+
+import (
+	"testing"
+
+	"github.com/hculpan/htc/examples"
+)
+
+func TestReconstruct(t *testing.T) {
+	input := "int i = 0;\n\ti++;\n/* a\n  b */ x=\"s t\"; // end\n"
+
+	expected := "int i = 0;\n i++;\n/* a\n  b */ x=\"s t\"; // end\n"
+
+	result := Reconstruct(NewLexer(input).Tokens())
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestReconstructLineEndings(t *testing.T) {
+	for _, input := range []string{"/*a\rb*/ x", "/*a\r\nb*/ x\r\ny", "/*a\n\rb*/  x"} {
+		original := NewLexer(input).Tokens()
+		relexed := NewLexer(Reconstruct(original)).Tokens()
+		if len(relexed) != len(original) {
+			t.Errorf("%q: expected %d tokens, got %d", input, len(original), len(relexed))
+			continue
+		}
+		for idx, tok := range original {
+			if tok.Line != relexed[idx].Line || tok.Position != relexed[idx].Position {
+				t.Errorf("%q: expected %+v, got %+v", input, tok, relexed[idx])
+			}
+		}
+	}
+}
+
+func TestReconstructWithTrivia(t *testing.T) {
+	inputs := []string{
+		"int i; // count\n\t/* a\n b */ i++;\n",
+		"x = \"\\x41\\'\\\"\";",
+		"s = \"open",
+		"x",
+	}
+	for _, input := range inputs {
+		result := Reconstruct(NewLexerWithOptions(input, Options{Trivia: true}).Tokens())
+		if result != input {
+			t.Errorf("expected %q, got %q", input, result)
+		}
+	}
+}
+
+func TestReconstructWithoutPositions(t *testing.T) {
+	tokens := []Token{
+		{Type: INT_TYPE, Literal: "int"},
+		{Type: IDENT, Literal: "x"},
+		{Type: SEMICOLON, Literal: ";"},
+	}
+
+	result := Reconstruct(tokens)
+	if result != "int x ;" {
+		t.Errorf("expected 'int x ;', got '%s'", result)
+	}
+}
+
+func TestReconstructRoundTrip(t *testing.T) {
+	for _, ex := range examples.All() {
+		original := NewLexer(ex.Source).Tokens()
+		printed := Reconstruct(original)
+		relexed := NewLexer(printed).Tokens()
+
+		if len(original) != len(relexed) {
+			t.Errorf("%s: expected %d tokens, got %d tokens", ex.Name, len(original), len(relexed))
+			continue
+		}
+		for idx, tok := range original {
+			if tok != relexed[idx] {
+				t.Errorf("%s: expected %+v, got %+v", ex.Name, tok, relexed[idx])
+				break
+			}
+		}
+		if again := Reconstruct(relexed); again != printed {
+			t.Errorf("%s: printing is not stable", ex.Name)
+		}
+
+		if printed := Reconstruct(NewLexerWithOptions(ex.Source, Options{Trivia: true}).Tokens()); printed != ex.Source {
+			t.Errorf("%s: expected trivia to reproduce the source, got %q", ex.Name, printed)
+		}
+	}
+}
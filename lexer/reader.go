@@ -0,0 +1,133 @@
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unsafe"
+)
+
+// readChunkSize is how much input a streaming lexer reads at a time.
+const readChunkSize = 4096
+
+// NewLexerFromReader initializes a Lexer that reads its input from r as
+// it goes. Only the text of the token being lexed and a small read-ahead
+// are buffered, so large files and piped input need not be loaded whole.
+// Read errors other than io.EOF are reported as lexer errors and end the
+// input.
+//
+// A streaming lexer does not retain the text it has passed, so
+// LineIndex returns nil.
+func NewLexerFromReader(r io.Reader) *Lexer {
+	return NewLexerFromReaderWithOptions(r, Options{})
+}
+
+// ResetReader prepares l to lex the input of r, as if it were a new
+// Lexer from NewLexerFromReader with the same options. Like Reset, it
+// drops everything from before but keeps buffers for reuse.
+func (l *Lexer) ResetReader(r io.Reader) {
+	l.resetReader(r, l.options())
+}
+
+// resetReader starts lexing the input of r with opts, keeping only l's
+// reusable buffers.
+func (l *Lexer) resetReader(r io.Reader, opts Options) {
+	l.clear()
+	l.reader, l.streaming = r, true
+	l.apply(opts)
+	l.ensure(utf16Prefix - 1)
+	l.normalize()
+	l.readChar()
+}
+
+// ensure makes sure the byte at position is buffered if the input has
+// one, and reports whether it does.
+func (l *Lexer) ensure(position int) bool {
+	for position-l.base >= len(l.input) && l.reader != nil {
+		l.fill()
+	}
+	return position-l.base < len(l.input)
+}
+
+// fill appends the next read from the reader to the buffer. input is a
+// string view of buf, so bytes it has exposed are never overwritten:
+// reads go into buf's spare capacity, and a full buffer is replaced by a
+// new one holding only the bytes from base on, at least twice their
+// size. A long token is therefore read in linear time.
+func (l *Lexer) fill() {
+	if cap(l.buf)-len(l.buf) < readChunkSize {
+		grown := make([]byte, len(l.buf), max(2*len(l.buf), len(l.buf)+readChunkSize))
+		copy(grown, l.buf)
+		l.buf = grown
+	}
+	n, err := l.reader.Read(l.buf[len(l.buf):cap(l.buf)])
+	l.buf = l.buf[:len(l.buf)+n]
+	l.input = unsafe.String(unsafe.SliceData(l.buf), len(l.buf))
+	if err == io.EOF {
+		l.reader = nil
+	} else if err != nil {
+		// report the error where the input was cut off
+		column := l.advanceColumn(l.column(), l.input[l.position-l.base:])
+		l.addErrorAt(l.line, column, fmt.Sprintf("read error: %s", err))
+		l.reader = nil
+	} else if l.limits.MaxFileSize > 0 && l.base+len(l.input) > l.limits.MaxFileSize {
+		l.buf = l.buf[:l.limits.MaxFileSize-l.base]
+		l.input = l.input[:l.limits.MaxFileSize-l.base]
+		column := l.advanceColumn(l.column(), l.input[l.position-l.base:])
+		l.addErrorAt(l.line, column, fmt.Sprintf("input exceeds the limit of %d bytes", l.limits.MaxFileSize))
+		l.reader = nil
+	}
+}
+
+// discard drops buffered input before the current char, or before the
+// pending trivia in trivia mode. It is called at the start of each token,
+// once nothing before it is needed.
+func (l *Lexer) discard() {
+	keep := l.position
+	if l.trivia {
+		keep = min(keep, l.triviaStart)
+	}
+	if l.streaming && keep > l.base && keep-l.base <= len(l.input) {
+		l.column() // count the current line's columns before they go
+		l.buf = l.buf[keep-l.base:]
+		l.input = l.input[keep-l.base:]
+		l.base = keep
+	}
+}
+
+// maxInterned caps the identifiers a streaming lexer shares, so input
+// made of ever new names cannot grow the map without bound.
+const maxInterned = 4096
+
+// intern returns the literal of tok, the token just lexed by a streaming
+// lexer, as a string that does not share memory with the input buffer.
+// A literal sliced from the buffer would keep the whole chunk it was read
+// in alive after discard drops it. Operators and keywords are spelled
+// like their token type, which is used instead. Identifiers are shared,
+// up to maxInterned of them, so a program's names are each allocated
+// once; other literals, such as strings and numbers, rarely repeat and
+// are copied.
+func (l *Lexer) intern(tok Token) string {
+	if string(tok.Type) == tok.Literal {
+		return string(tok.Type)
+	}
+	if tok.Type != IDENT {
+		return strings.Clone(tok.Literal)
+	}
+	if literal, ok := l.interned[tok.Literal]; ok {
+		return literal
+	}
+	literal := strings.Clone(tok.Literal)
+	if len(l.interned) < maxInterned {
+		if l.interned == nil {
+			l.interned = map[string]string{}
+		}
+		l.interned[literal] = literal
+	}
+	return literal
+}
+
+// slice returns the input between the positions start and end.
+func (l *Lexer) slice(start int, end int) string {
+	return l.input[start-l.base : end-l.base]
+}
@@ -0,0 +1,136 @@
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unsafe"
+
+	"github.com/hculpan/htc/examples"
+)
+
+func TestLexerFromReaderMatchesString(t *testing.T) {
+	inputs := []string{
+		"\xef\xbb\xbfint x;\r\n/* a\r\nb */ y = \"s\\n\" <<= 0x1F;",
+		"a\rb",
+		"s = \"é😀\"; naïve € y\n\t日本",
+		"",
+	}
+	for _, ex := range examples.All() {
+		inputs = append(inputs, ex.Source)
+	}
+
+	for _, input := range inputs {
+		expected := NewLexer(input)
+		expectedTokens := expected.Tokens()
+
+		readers := map[string]io.Reader{
+			"whole":    strings.NewReader(input),
+			"one byte": iotest.OneByteReader(strings.NewReader(input)),
+			"half":     iotest.HalfReader(strings.NewReader(input)),
+		}
+		for name, r := range readers {
+			l := NewLexerFromReader(r)
+			tokens := l.Tokens()
+			if len(tokens) != len(expectedTokens) {
+				t.Errorf("%s %q: expected %d tokens, got %d", name, input, len(expectedTokens), len(tokens))
+				continue
+			}
+			for idx, tok := range tokens {
+				if tok != expectedTokens[idx] {
+					t.Errorf("%s %q: expected %+v, got %+v", name, input, expectedTokens[idx], tok)
+					break
+				}
+			}
+			if l.LineEnding() != expected.LineEnding() {
+				t.Errorf("%s %q: expected line ending %q, got %q", name, input, expected.LineEnding(), l.LineEnding())
+			}
+			if l.LineIndex() != nil {
+				t.Errorf("%s: expected no line index for a streaming lexer", name)
+			}
+		}
+	}
+}
+
+func TestLexerFromReaderBuffersLittle(t *testing.T) {
+	line := "int value = (a + b) * 42; // comment\n"
+	input := strings.Repeat(line, 50000)
+
+	l := NewLexerFromReader(strings.NewReader(input))
+	count, largest := 0, 0
+	for {
+		tok := l.NextToken()
+		largest = max(largest, len(l.input))
+		if tok.Type == EOF {
+			break
+		}
+		count++
+	}
+
+	if count != 50000*12 {
+		t.Errorf("expected %d tokens, got %d", 50000*12, count)
+	}
+	if largest > 2*readChunkSize {
+		t.Errorf("expected at most %d buffered bytes, got %d", 2*readChunkSize, largest)
+	}
+}
+
+func TestLexerFromReaderLongToken(t *testing.T) {
+	input := "/*" + strings.Repeat("x", 4<<20) + "*/ y"
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	tokens := NewLexerFromReader(strings.NewReader(input)).Tokens()
+	runtime.ReadMemStats(&after)
+
+	if len(tokens) != 3 || tokens[0].Literal != input[:len(input)-2] {
+		t.Fatalf("expected the comment, y and EOF, got %d tokens", len(tokens))
+	}
+	// copying the buffer on every read would allocate gigabytes
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 8*uint64(len(input)) {
+		t.Errorf("expected buffering to allocate in proportion to the token, got %d bytes", allocated)
+	}
+}
+
+func TestLexerFromReaderError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("int x"), iotest.ErrReader(errors.New("disk on fire")))
+
+	l := NewLexerFromReader(r)
+	expected := []ExpectedToken{
+		{Type: "int", Literal: "int"},
+		{Type: "IDENT", Literal: "x"},
+		{Type: "EOF", Literal: ""},
+	}
+	validateTokens(expected, l, t)
+	expectErrors(t, l, "[1:6] read error: disk on fire")
+}
+
+func TestReaderInternsLiterals(t *testing.T) {
+	tokens := NewLexerFromReader(strings.NewReader("count = count + 1;")).Tokens()
+	if unsafe.StringData(tokens[0].Literal) != unsafe.StringData(tokens[2].Literal) {
+		t.Errorf("expected both count literals to share one string")
+	}
+}
+
+func TestReaderInternsOnlyIdentifiers(t *testing.T) {
+	var sb strings.Builder
+	for i := range 3 * maxInterned {
+		fmt.Fprintf(&sb, "name%d = \"text %d\" + %d;\n", i, i, i)
+	}
+
+	l := NewLexerFromReader(strings.NewReader(sb.String()))
+	for l.NextToken().Type != EOF {
+	}
+	if len(l.interned) != maxInterned {
+		t.Errorf("expected %d interned literals, got %d", maxInterned, len(l.interned))
+	}
+	for literal := range l.interned {
+		if !strings.HasPrefix(literal, "name") {
+			t.Errorf("expected only identifiers to be interned, found %q", literal)
+		}
+	}
+}
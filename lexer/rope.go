@@ -0,0 +1,127 @@
+package lexer
+
+import (
+	"io"
+	"slices"
+	"strings"
+)
+
+// ropeChunk is the size a rope splits its text into. Edits leave chunks
+// of up to twice this size alone and merge pieces smaller than half of
+// it into a neighbor.
+const ropeChunk = 4096
+
+// rope is text kept as a list of chunks, so replacing part of it copies
+// only the chunks around the replaced range rather than the whole text.
+type rope struct {
+	chunks []string
+	size   int
+}
+
+// newRope returns a rope holding text. The chunks are substrings of
+// text, so nothing is copied.
+func newRope(text string) *rope {
+	return &rope{chunks: splitChunks(nil, text), size: len(text)}
+}
+
+// splitChunks appends text to chunks in pieces of about ropeChunk bytes.
+func splitChunks(chunks []string, text string) []string {
+	for len(text) > 2*ropeChunk {
+		chunks = append(chunks, text[:ropeChunk])
+		text = text[ropeChunk:]
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// locate returns the index of the chunk holding offset and the offset
+// within it. The end of the text is located just past the last chunk.
+func (r *rope) locate(offset int) (int, int) {
+	for idx, chunk := range r.chunks {
+		if offset < len(chunk) {
+			return idx, offset
+		}
+		offset -= len(chunk)
+	}
+	return len(r.chunks), 0
+}
+
+// replace replaces the text between start and end with text.
+func (r *rope) replace(start int, end int, text string) {
+	first, startOffset := r.locate(start)
+	last, endOffset := r.locate(end)
+
+	var sb strings.Builder
+	if first < len(r.chunks) {
+		sb.WriteString(r.chunks[first][:startOffset])
+	}
+	sb.WriteString(text)
+	if last < len(r.chunks) {
+		sb.WriteString(r.chunks[last][endOffset:])
+		last++
+	}
+	merged := sb.String()
+	if len(merged) < ropeChunk/2 {
+		// keep small edits from leaving ever more tiny chunks
+		if last < len(r.chunks) {
+			merged += r.chunks[last]
+			last++
+		} else if first > 0 {
+			first--
+			merged = r.chunks[first] + merged
+		}
+	}
+
+	r.chunks = slices.Replace(r.chunks, first, last, splitChunks(nil, merged)...)
+	r.size += len(text) - (end - start)
+}
+
+// slice returns the text between start and end. Text within one chunk
+// is returned without copying.
+func (r *rope) slice(start int, end int) string {
+	idx, offset := r.locate(start)
+	if idx < len(r.chunks) && offset+end-start <= len(r.chunks[idx]) {
+		return r.chunks[idx][offset : offset+end-start]
+	}
+	var sb strings.Builder
+	sb.Grow(end - start)
+	for ; sb.Len() < end-start; idx++ {
+		chunk := r.chunks[idx][offset:]
+		sb.WriteString(chunk[:min(len(chunk), end-start-sb.Len())])
+		offset = 0
+	}
+	return sb.String()
+}
+
+// String returns the whole text.
+func (r *rope) String() string {
+	return r.slice(0, r.size)
+}
+
+// reader returns a reader for the text from offset on. The rope must not
+// be changed while the reader is in use.
+func (r *rope) reader(offset int) io.Reader {
+	idx, within := r.locate(offset)
+	return &ropeReader{rope: r, chunk: idx, offset: within}
+}
+
+type ropeReader struct {
+	rope   *rope
+	chunk  int
+	offset int
+}
+
+func (rr *ropeReader) Read(p []byte) (int, error) {
+	for rr.chunk < len(rr.rope.chunks) && rr.offset == len(rr.rope.chunks[rr.chunk]) {
+		rr.chunk++
+		rr.offset = 0
+	}
+	if rr.chunk == len(rr.rope.chunks) {
+		return 0, io.EOF
+	}
+	n := copy(p, rr.rope.chunks[rr.chunk][rr.offset:])
+	rr.offset += n
+	return n, nil
+}
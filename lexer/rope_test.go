@@ -0,0 +1,37 @@
+package lexer
+
+import (
+	"io"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+func TestRopeMatchesString(t *testing.T) {
+	rng := rand.New(rand.NewPCG(4, 5))
+	sizes := []int{0, 1, 10, ropeChunk, 3 * ropeChunk}
+	text := strings.Repeat("0123456789abcdef", 3*ropeChunk/16)
+	r := newRope(text)
+	for range 500 {
+		start := rng.IntN(len(text) + 1)
+		end := start + rng.IntN(min(len(text)-start, ropeChunk)+1)
+		newText := strings.Repeat("x", sizes[rng.IntN(len(sizes))])
+		r.replace(start, end, newText)
+		text = text[:start] + newText + text[end:]
+
+		if r.size != len(text) || r.String() != text {
+			t.Fatalf("replacing %d-%d with %d bytes: rope does not match", start, end, len(newText))
+		}
+		from := rng.IntN(len(text) + 1)
+		to := from + rng.IntN(len(text)-from+1)
+		if got := r.slice(from, to); got != text[from:to] {
+			t.Fatalf("slice %d-%d: expected %q, got %q", from, to, text[from:to], got)
+		}
+		if got, err := io.ReadAll(r.reader(from)); err != nil || string(got) != text[from:] {
+			t.Fatalf("reading from %d: expected %d bytes, got %d (%v)", from, len(text)-from, len(got), err)
+		}
+	}
+	if len(r.chunks) > 2*len(text)/ropeChunk+2 {
+		t.Errorf("expected edits to keep chunks large, got %d for %d bytes", len(r.chunks), len(text))
+	}
+}
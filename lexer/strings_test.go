@@ -0,0 +1,150 @@
+package lexer
+
+import (
+	"testing"
+)
+
+func TestStringEscapes(t *testing.T) {
+	input := `"a\nb" "\t\r\\\"\'" "\0" "\x41\x7a\xFF" "say \"hi\""`
+
+	expected := []ExpectedToken{
+		{Type: "STRING", Literal: "a\nb"},
+		{Type: "STRING", Literal: "\t\r\\\"'"},
+		{Type: "STRING", Literal: "\x00"},
+		{Type: "STRING", Literal: "Az\xff"},
+		{Type: "STRING", Literal: `say "hi"`},
+		{Type: "EOF", Literal: ""},
+	}
+	l := NewLexer(input)
+	validateTokens(expected, l, t)
+	expectErrors(t, l)
+}
+
+func TestInvalidStringEscapes(t *testing.T) {
+	input := `x = "a\qb\x4" ;`
+
+	expected := []ExpectedToken{
+		{Type: "IDENT", Literal: "x"},
+		{Type: "=", Literal: "="},
+		{Type: "STRING", Literal: `a\qb\x4`},
+		{Type: ";", Literal: ";"},
+		{Type: "EOF", Literal: ""},
+	}
+	l := NewLexer(input)
+	validateTokens(expected, l, t)
+	expectErrors(t, l,
+		"[1:7] invalid escape sequence '\\q'",
+		"[1:10] \\x escape requires two hex digits",
+	)
+}
+
+func TestUnterminatedStrings(t *testing.T) {
+	inputs := []string{`"abc`, `"abc\`, "\"abc\\\n"}
+
+	for _, input := range inputs {
+		l := NewLexer(input)
+		tokens := l.Tokens()
+		if len(tokens) != 2 || tokens[0].Type != STRING || tokens[1].Type != EOF {
+			t.Errorf("%q: expected STRING and EOF, got %v", input, tokens)
+		} else if !tokens[0].Unterminated {
+			t.Errorf("%q: expected the string to be marked unterminated", input)
+		}
+		if len(l.Errors()) != 1 {
+			t.Errorf("%q: expected 1 error, got %v", input, l.Errors())
+		}
+	}
+}
+
+func TestStringRecovery(t *testing.T) {
+	input := "x = \"ab\ncd\";\ny;"
+	tests := []struct {
+		recovery StringRecovery
+		expected []Token
+	}{
+		{RecoverAtNewline, []Token{
+			{Type: STRING, Literal: "ab", Line: 1, Position: 5, Unterminated: true},
+			{Type: IDENT, Literal: "cd", Line: 2, Position: 1},
+			{Type: STRING, Literal: ";", Line: 2, Position: 3, Unterminated: true},
+			{Type: IDENT, Literal: "y", Line: 3, Position: 1},
+			{Type: SEMICOLON, Literal: ";", Line: 3, Position: 2},
+			{Type: EOF, Literal: "", Line: 3, Position: 3},
+		}},
+		{RecoverAtQuote, []Token{
+			{Type: STRING, Literal: "ab\ncd", Line: 1, Position: 5, Unterminated: true},
+			{Type: SEMICOLON, Literal: ";", Line: 2, Position: 4},
+			{Type: IDENT, Literal: "y", Line: 3, Position: 1},
+			{Type: SEMICOLON, Literal: ";", Line: 3, Position: 2},
+			{Type: EOF, Literal: "", Line: 3, Position: 3},
+		}},
+		{StopAtUnterminated, []Token{
+			{Type: STRING, Literal: "ab", Line: 1, Position: 5, Unterminated: true},
+			{Type: EOF, Literal: "", Line: 1, Position: 8},
+		}},
+	}
+	for _, tt := range tests {
+		l := NewLexerWithOptions(input, Options{StringRecovery: tt.recovery})
+		tokens := l.Tokens()[2:]
+		if len(tokens) != len(tt.expected) {
+			t.Errorf("recovery %d: expected %d tokens, got %+v", tt.recovery, len(tt.expected), tokens)
+			continue
+		}
+		for idx, tok := range tokens {
+			expected := tt.expected[idx]
+			if tok.Type != expected.Type || tok.Literal != expected.Literal || tok.Line != expected.Line ||
+				tok.Position != expected.Position || tok.Unterminated != expected.Unterminated {
+				t.Errorf("recovery %d: token %d: expected %+v, got %+v", tt.recovery, idx, expected, tok)
+			}
+		}
+		if len(l.Errors()) == 0 || l.Errors()[0].Error() != "[1:5] non-terminated string" {
+			t.Errorf("recovery %d: expected the string reported first, got %v", tt.recovery, l.Errors())
+		}
+	}
+}
+
+func TestQuoteString(t *testing.T) {
+	value := "a\n\t\r\x00\\\"\x01é"
+	quoted := quoteString(value)
+	if quoted != `"a\n\t\r\0\\\"\x01é"` {
+		t.Errorf("unexpected quoting %s", quoted)
+	}
+
+	tokens := NewLexer(quoted).Tokens()
+	if tokens[0].Literal != value {
+		t.Errorf("expected %q, got %q", value, tokens[0].Literal)
+	}
+}
+
+func TestUTF8Columns(t *testing.T) {
+	input := "s = \"é😀\"; naïve € y\n\"日本\\q\""
+
+	expected := []Token{
+		{Type: IDENT, Literal: "s", Line: 1, Position: 1, EndLine: 1, EndColumn: 2, StartOffset: 0, EndOffset: 1},
+		{Type: ASSIGN, Literal: "=", Line: 1, Position: 3, EndLine: 1, EndColumn: 4, StartOffset: 2, EndOffset: 3},
+		{Type: STRING, Literal: "é😀", Line: 1, Position: 5, EndLine: 1, EndColumn: 9, StartOffset: 4, EndOffset: 12},
+		{Type: SEMICOLON, Literal: ";", Line: 1, Position: 9, EndLine: 1, EndColumn: 10, StartOffset: 12, EndOffset: 13},
+		{Type: IDENT, Literal: "naïve", Line: 1, Position: 11, EndLine: 1, EndColumn: 16, StartOffset: 14, EndOffset: 20},
+		{Type: ILLEGAL, Literal: "€", Line: 1, Position: 17, EndLine: 1, EndColumn: 18, StartOffset: 21, EndOffset: 24},
+		{Type: IDENT, Literal: "y", Line: 1, Position: 19, EndLine: 1, EndColumn: 20, StartOffset: 25, EndOffset: 26},
+		{Type: STRING, Literal: "日本\\q", Line: 2, Position: 1, EndLine: 2, EndColumn: 7, StartOffset: 27, EndOffset: 37},
+		{Type: EOF, Literal: "", Line: 2, Position: 7, EndLine: 2, EndColumn: 7, StartOffset: 37, EndOffset: 37},
+	}
+
+	l := NewLexer(input)
+	tokens := l.Tokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %+v", len(expected), tokens)
+	}
+	for idx, tok := range tokens {
+		if tok != expected[idx] {
+			t.Errorf("expected %+v, got %+v", expected[idx], tok)
+		}
+	}
+	expectErrors(t, l, "[2:4] invalid escape sequence '\\q'")
+
+	idx := l.LineIndex()
+	for _, tok := range tokens {
+		if line, column := idx.PositionFor(tok.StartOffset); line != tok.Line || column != tok.Position {
+			t.Errorf("offset %d maps to %d:%d, expected %d:%d", tok.StartOffset, line, column, tok.Line, tok.Position)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+// Code generated by tokengen from internal/tokengen/spec.go; DO NOT EDIT.
+
+package lexer
+
+// Operator token types
+const (
+	ASSIGN           = "="
+	INCREMENT        = "++"
+	DECREMENT        = "--"
+	PLUS_EQUALS      = "+="
+	MINUS_EQUALS     = "-="
+	ASTERISK_EQUALS  = "*="
+	SLASH_EQUALS     = "/="
+	PERCENT_EQUALS   = "%="
+	AMPERSAND_EQUALS = "&="
+	PIPE_EQUALS      = "|="
+	CARET_EQUALS     = "^="
+	LSHIFT_EQUALS    = "<<="
+	RSHIFT_EQUALS    = ">>="
+	PLUS             = "+"
+	MINUS            = "-"
+	ASTERISK         = "*"
+	SLASH            = "/"
+	PERCENT          = "%"
+	BANG             = "!"
+	EQ               = "=="
+	NEQ              = "!="
+	LT               = "<"
+	GT               = ">"
+	LE               = "<="
+	GE               = ">="
+	AND              = "&&"
+	OR               = "||"
+	AMPERSAND        = "&"
+	PIPE             = "|"
+	CARET            = "^"
+	TILDE            = "~"
+	LSHIFT           = "<<"
+	RSHIFT           = ">>"
+	LPAREN           = "("
+	RPAREN           = ")"
+	LBRACKET         = "["
+	RBRACKET         = "]"
+	LBRACE           = "{"
+	RBRACE           = "}"
+	COMMA            = ","
+	PERIOD           = "."
+	ARROW            = "->"
+	ELLIPSIS         = "..."
+	SEMICOLON        = ";"
+	QUESTION         = "?"
+	COLON            = ":"
+	HASH             = "#"
+)
+
+// Keyword token types
+const (
+	IF          = "if"
+	ELSE        = "else"
+	WHILE       = "while"
+	RETURN      = "return"
+	INT_TYPE    = "int"
+	VOID_TYPE   = "void"
+	CHAR_TYPE   = "char"
+	FLOAT_TYPE  = "float"
+	DOUBLE_TYPE = "double"
+	STRUCT      = "struct"
+	UNION       = "union"
+	ENUM        = "enum"
+	TYPEDEF     = "typedef"
+	CONST       = "const"
+	STATIC      = "static"
+	EXTERN      = "extern"
+	UNSIGNED    = "unsigned"
+	SIGNED      = "signed"
+	LONG        = "long"
+	SHORT       = "short"
+	SIZEOF      = "sizeof"
+	FOR         = "for"
+	DO          = "do"
+	BREAK       = "break"
+	CONTINUE    = "continue"
+	SWITCH      = "switch"
+	CASE        = "case"
+	DEFAULT     = "default"
+	GOTO        = "goto"
+	PRINTF      = "printf"
+)
+
+// keywords maps each reserved word to its token type.
+var keywords = map[string]TokenType{
+	"if":       IF,
+	"else":     ELSE,
+	"while":    WHILE,
+	"return":   RETURN,
+	"int":      INT_TYPE,
+	"void":     VOID_TYPE,
+	"char":     CHAR_TYPE,
+	"float":    FLOAT_TYPE,
+	"double":   DOUBLE_TYPE,
+	"struct":   STRUCT,
+	"union":    UNION,
+	"enum":     ENUM,
+	"typedef":  TYPEDEF,
+	"const":    CONST,
+	"static":   STATIC,
+	"extern":   EXTERN,
+	"unsigned": UNSIGNED,
+	"signed":   SIGNED,
+	"long":     LONG,
+	"short":    SHORT,
+	"sizeof":   SIZEOF,
+	"for":      FOR,
+	"do":       DO,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"switch":   SWITCH,
+	"case":     CASE,
+	"default":  DEFAULT,
+	"goto":     GOTO,
+	"printf":   PRINTF,
+}
+
+// operatorStates is the operator trie walked by readOperator.
+var operatorStates = []operatorState{
+	// start
+	{edges: "!#%&()*+,-./:;<=>?[]^{|}~", targets: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25}},
+	// "!"
+	{tokenType: BANG, edges: "=", targets: []int{26}},
+	// "#"
+	{tokenType: HASH},
+	// "%"
+	{tokenType: PERCENT, edges: "=", targets: []int{27}},
+	// "&"
+	{tokenType: AMPERSAND, edges: "&=", targets: []int{28, 29}},
+	// "("
+	{tokenType: LPAREN},
+	// ")"
+	{tokenType: RPAREN},
+	// "*"
+	{tokenType: ASTERISK, edges: "=", targets: []int{30}},
+	// "+"
+	{tokenType: PLUS, edges: "+=", targets: []int{31, 32}},
+	// ","
+	{tokenType: COMMA},
+	// "-"
+	{tokenType: MINUS, edges: "-=>", targets: []int{33, 34, 35}},
+	// "."
+	{tokenType: PERIOD, edges: ".", targets: []int{36}},
+	// "/"
+	{tokenType: SLASH, edges: "=", targets: []int{37}},
+	// ":"
+	{tokenType: COLON},
+	// ";"
+	{tokenType: SEMICOLON},
+	// "<"
+	{tokenType: LT, edges: "<=", targets: []int{38, 39}},
+	// "="
+	{tokenType: ASSIGN, edges: "=", targets: []int{40}},
+	// ">"
+	{tokenType: GT, edges: "=>", targets: []int{41, 42}},
+	// "?"
+	{tokenType: QUESTION},
+	// "["
+	{tokenType: LBRACKET},
+	// "]"
+	{tokenType: RBRACKET},
+	// "^"
+	{tokenType: CARET, edges: "=", targets: []int{43}},
+	// "{"
+	{tokenType: LBRACE},
+	// "|"
+	{tokenType: PIPE, edges: "=|", targets: []int{44, 45}},
+	// "}"
+	{tokenType: RBRACE},
+	// "~"
+	{tokenType: TILDE},
+	// "!="
+	{tokenType: NEQ},
+	// "%="
+	{tokenType: PERCENT_EQUALS},
+	// "&&"
+	{tokenType: AND},
+	// "&="
+	{tokenType: AMPERSAND_EQUALS},
+	// "*="
+	{tokenType: ASTERISK_EQUALS},
+	// "++"
+	{tokenType: INCREMENT},
+	// "+="
+	{tokenType: PLUS_EQUALS},
+	// "--"
+	{tokenType: DECREMENT},
+	// "-="
+	{tokenType: MINUS_EQUALS},
+	// "->"
+	{tokenType: ARROW},
+	// ".."
+	{edges: ".", targets: []int{46}},
+	// "/="
+	{tokenType: SLASH_EQUALS},
+	// "<<"
+	{tokenType: LSHIFT, edges: "=", targets: []int{47}},
+	// "<="
+	{tokenType: LE},
+	// "=="
+	{tokenType: EQ},
+	// ">="
+	{tokenType: GE},
+	// ">>"
+	{tokenType: RSHIFT, edges: "=", targets: []int{48}},
+	// "^="
+	{tokenType: CARET_EQUALS},
+	// "|="
+	{tokenType: PIPE_EQUALS},
+	// "||"
+	{tokenType: OR},
+	// "..."
+	{tokenType: ELLIPSIS},
+	// "<<="
+	{tokenType: LSHIFT_EQUALS},
+	// ">>="
+	{tokenType: RSHIFT_EQUALS},
+}
@@ -0,0 +1,313 @@
+package lexer
+
+import (
+	"slices"
+	"sort"
+)
+
+// tokenChunkSize is the number of tokens a tokenStore keeps per chunk.
+// Edits leave chunks of up to twice this size alone and merge chunks
+// smaller than a quarter of it into a neighbor.
+const tokenChunkSize = 64
+
+// tokenChunk is a run of a document's tokens and the diagnostics
+// reported while lexing them. Moving them after an edit adds to offset
+// and line instead of rewriting every one. The remaining fields
+// summarize the chunk for the document's limits.
+type tokenChunk struct {
+	tokens      []Token
+	diagnostics []diagnostic // in the order reported
+	offset      int          // added to the offsets of tokens and diagnostics
+	line        int          // added to their lines
+	count       int          // tokens other than EOF
+	errors      int          // diagnostics that are errors
+	parens      nesting      // of ( and [
+	braces      nesting      // of {
+}
+
+// diagnostic is an error or warning of a document, kept with the offset
+// of the token being lexed when it was reported.
+type diagnostic struct {
+	offset  int
+	warning bool
+	err     Error
+}
+
+// nesting summarizes how a run of tokens changes a nesting depth that
+// never drops below zero, the way withinLimits counts it. Entered at
+// depth d, the run reaches max(d+high, rise) and leaves at
+// net+max(d, -low).
+type nesting struct {
+	net  int // change in depth, ignoring the floor at zero
+	low  int // lowest change reached
+	high int // highest change reached
+	rise int // highest change reached above the lowest one before it
+}
+
+func (n *nesting) add(change int) {
+	n.net += change
+	n.low = min(n.low, n.net)
+	n.high = max(n.high, n.net)
+	n.rise = max(n.rise, n.net-n.low)
+}
+
+func newTokenChunk(tokens []Token, diagnostics []diagnostic) *tokenChunk {
+	c := &tokenChunk{tokens: tokens, diagnostics: diagnostics}
+	for _, tok := range tokens {
+		switch tok.Type {
+		case EOF:
+			continue
+		case LPAREN, LBRACKET:
+			c.parens.add(1)
+		case RPAREN, RBRACKET:
+			c.parens.add(-1)
+		case LBRACE:
+			c.braces.add(1)
+		case RBRACE:
+			c.braces.add(-1)
+		}
+		c.count++
+	}
+	for _, diag := range diagnostics {
+		if !diag.warning {
+			c.errors++
+		}
+	}
+	return c
+}
+
+// token returns the idx-th token of c at its current position.
+func (c *tokenChunk) token(idx int) Token {
+	tok := c.tokens[idx]
+	tok.StartOffset += c.offset
+	tok.EndOffset += c.offset
+	tok.Line += c.line
+	tok.EndLine += c.line
+	return tok
+}
+
+// diagnostic returns the idx-th diagnostic of c at its current position.
+func (c *tokenChunk) diagnostic(idx int) diagnostic {
+	diag := c.diagnostics[idx]
+	diag.offset += c.offset
+	if diag.err.Line != 0 {
+		diag.err.Line += c.line
+	}
+	return diag
+}
+
+// searchDiagnostics returns the index of the first diagnostic of c
+// reported for the token at offset or a later one.
+func (c *tokenChunk) searchDiagnostics(offset int) int {
+	return sort.Search(len(c.diagnostics), func(i int) bool {
+		return c.diagnostics[i].offset+c.offset >= offset
+	})
+}
+
+// appendTo appends the tokens of c from start to end to tokens, moved by
+// offset and line past their current position.
+func (c *tokenChunk) appendTo(tokens []Token, start int, end int, offset int, line int) []Token {
+	for idx := start; idx < end; idx++ {
+		tok := c.token(idx)
+		tok.StartOffset += offset
+		tok.EndOffset += offset
+		tok.Line += line
+		tok.EndLine += line
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+// appendDiagnosticsTo is appendTo for the diagnostics of c.
+func (c *tokenChunk) appendDiagnosticsTo(diagnostics []diagnostic, start int, end int, offset int, line int) []diagnostic {
+	for idx := start; idx < end; idx++ {
+		diag := c.diagnostic(idx)
+		diag.offset += offset
+		if diag.err.Line != 0 {
+			diag.err.Line += line
+		}
+		diagnostics = append(diagnostics, diag)
+	}
+	return diagnostics
+}
+
+// tokenStore holds the tokens of a document in chunks, so an edit
+// rewrites only the chunks it touches. It is never empty, since a
+// document ends with EOF.
+type tokenStore struct {
+	chunks []*tokenChunk
+}
+
+// cursor is the position of a token in a tokenStore. The cursor past the
+// last token has chunk set to the number of chunks.
+type cursor struct {
+	chunk int
+	index int
+}
+
+func newTokenStore(tokens []Token, diagnostics []diagnostic) tokenStore {
+	return tokenStore{chunks: splitTokens(nil, tokens, diagnostics)}
+}
+
+// splitTokens appends tokens to chunks in runs of about tokenChunkSize,
+// each with the diagnostics reported for its tokens.
+func splitTokens(chunks []*tokenChunk, tokens []Token, diagnostics []diagnostic) []*tokenChunk {
+	for len(tokens) > 2*tokenChunkSize {
+		rest := tokens[tokenChunkSize:]
+		split := sort.Search(len(diagnostics), func(i int) bool {
+			return diagnostics[i].offset >= rest[0].StartOffset
+		})
+		chunks = append(chunks, newTokenChunk(tokens[:tokenChunkSize:tokenChunkSize], diagnostics[:split:split]))
+		tokens, diagnostics = rest, diagnostics[split:]
+	}
+	if len(tokens) > 0 {
+		chunks = append(chunks, newTokenChunk(tokens, diagnostics))
+	}
+	return chunks
+}
+
+// valid reports whether c is at a token rather than past the last one.
+func (s *tokenStore) valid(c cursor) bool {
+	return c.chunk < len(s.chunks)
+}
+
+// at returns the token at c.
+func (s *tokenStore) at(c cursor) Token {
+	return s.chunks[c.chunk].token(c.index)
+}
+
+// next returns the cursor after c.
+func (s *tokenStore) next(c cursor) cursor {
+	if c.index+1 < len(s.chunks[c.chunk].tokens) {
+		return cursor{c.chunk, c.index + 1}
+	}
+	return cursor{c.chunk + 1, 0}
+}
+
+// prev returns the cursor before c, if there is one.
+func (s *tokenStore) prev(c cursor) (cursor, bool) {
+	switch {
+	case c.index > 0:
+		return cursor{c.chunk, c.index - 1}, true
+	case c.chunk > 0:
+		return cursor{c.chunk - 1, len(s.chunks[c.chunk-1].tokens) - 1}, true
+	}
+	return c, false
+}
+
+// search returns the cursor of the first token starting at or after
+// offset.
+func (s *tokenStore) search(offset int) cursor {
+	idx := sort.Search(len(s.chunks), func(i int) bool {
+		chunk := s.chunks[i]
+		return chunk.tokens[len(chunk.tokens)-1].StartOffset+chunk.offset >= offset
+	})
+	if idx == len(s.chunks) {
+		return cursor{idx, 0}
+	}
+	chunk := s.chunks[idx]
+	return cursor{idx, sort.Search(len(chunk.tokens), func(i int) bool {
+		return chunk.tokens[i].StartOffset+chunk.offset >= offset
+	})}
+}
+
+// index returns the number of tokens before c.
+func (s *tokenStore) index(c cursor) int {
+	count := c.index
+	for _, chunk := range s.chunks[:c.chunk] {
+		count += len(chunk.tokens)
+	}
+	return count
+}
+
+// all returns every token in a new slice.
+func (s *tokenStore) all() []Token {
+	tokens := make([]Token, 0, s.index(cursor{chunk: len(s.chunks)}))
+	for _, chunk := range s.chunks {
+		tokens = chunk.appendTo(tokens, 0, len(chunk.tokens), 0, 0)
+	}
+	return tokens
+}
+
+// diagnostics returns the errors and warnings of every chunk.
+func (s *tokenStore) diagnostics() ([]error, []error) {
+	errors, warnings := []error{}, []error{}
+	for _, chunk := range s.chunks {
+		for idx := range chunk.diagnostics {
+			diag := chunk.diagnostic(idx)
+			if diag.warning {
+				warnings = append(warnings, &diag.err)
+			} else {
+				errors = append(errors, &diag.err)
+			}
+		}
+	}
+	return errors, warnings
+}
+
+// shiftColumns adds delta to the columns of the tokens from c on that
+// start or end on line, and of the diagnostics reported for them that
+// are on line. Both come before any on later lines.
+func (s *tokenStore) shiftColumns(c cursor, line int, delta int) {
+	if delta == 0 || !s.valid(c) {
+		return
+	}
+	for t := c; s.valid(t) && s.at(t).Line == line; t = s.next(t) {
+		chunk := s.chunks[t.chunk]
+		chunk.tokens[t.index].Position += delta
+		if chunk.token(t.index).EndLine == line {
+			chunk.tokens[t.index].EndColumn += delta
+		}
+	}
+
+	idx := s.chunks[c.chunk].searchDiagnostics(s.at(c).StartOffset)
+	for _, chunk := range s.chunks[c.chunk:] {
+		for ; idx < len(chunk.diagnostics); idx++ {
+			switch diag := chunk.diagnostic(idx); {
+			case diag.err.Line > line:
+				return
+			case diag.err.Line == line:
+				chunk.diagnostics[idx].err.Column += delta
+			}
+		}
+		idx = 0
+	}
+}
+
+// replace replaces the tokens from start up to end, and the diagnostics
+// reported for them, with tokens and diagnostics, and moves the tokens
+// from end on by offset and line. Only the chunks holding start and end
+// are rewritten; later chunks just record the move.
+func (s *tokenStore) replace(start cursor, end cursor, tokens []Token, diagnostics []diagnostic, offset int, line int) {
+	first := s.chunks[start.chunk]
+	last := end.chunk
+	size := start.index + len(tokens)
+	if last < len(s.chunks) {
+		size += len(s.chunks[last].tokens) - end.index
+	}
+	merged := first.appendTo(make([]Token, 0, size), 0, start.index, 0, 0)
+	merged = append(merged, tokens...)
+	kept := first.searchDiagnostics(first.token(start.index).StartOffset)
+	mergedDiagnostics := first.appendDiagnosticsTo(nil, 0, kept, 0, 0)
+	mergedDiagnostics = append(mergedDiagnostics, diagnostics...)
+	if last < len(s.chunks) {
+		chunk := s.chunks[last]
+		merged = chunk.appendTo(merged, end.index, len(chunk.tokens), offset, line)
+		from := chunk.searchDiagnostics(chunk.token(end.index).StartOffset)
+		mergedDiagnostics = chunk.appendDiagnosticsTo(mergedDiagnostics, from, len(chunk.diagnostics), offset, line)
+		last++
+	}
+	if len(merged) < tokenChunkSize/4 && last < len(s.chunks) {
+		// keep small edits from leaving ever more tiny chunks
+		chunk := s.chunks[last]
+		merged = chunk.appendTo(merged, 0, len(chunk.tokens), offset, line)
+		mergedDiagnostics = chunk.appendDiagnosticsTo(mergedDiagnostics, 0, len(chunk.diagnostics), offset, line)
+		last++
+	}
+
+	for _, chunk := range s.chunks[last:] {
+		chunk.offset += offset
+		chunk.line += line
+	}
+	s.chunks = slices.Replace(s.chunks, start.chunk, last, splitTokens(nil, merged, mergedDiagnostics)...)
+}
@@ -0,0 +1,80 @@
+package lexer
+
+import "strings"
+
+// SetTrivia controls trivia mode. In trivia mode every token records the
+// whitespace and comments around it, and comments are not returned as
+// COMMENT tokens. A token's TrailingTrivia runs up to and including the
+// end of the line it ends on, stopping before any comment that continues
+// onto a later line; everything else between two tokens is the
+// LeadingTrivia of the second. Each token also records its source text
+// in Raw. Together with the raw text, the trivia covers the whole input,
+// which lossless formatters and doc comment attachment rely on.
+func (l *Lexer) SetTrivia(enabled bool) {
+	l.trivia = enabled
+	l.triviaStart = l.position
+}
+
+// attachTrivia fills in the trivia and source text of tok, the token
+// just lexed.
+func (l *Lexer) attachTrivia(tok *Token) {
+	end := l.trailingTriviaEnd(tok.EndOffset)
+	tok.LeadingTrivia = l.slice(l.triviaStart, tok.StartOffset)
+	tok.TrailingTrivia = l.slice(tok.EndOffset, end)
+	tok.Raw = l.slice(tok.StartOffset, tok.EndOffset)
+	if l.streaming {
+		// like literals, trivia must not view the read buffer
+		tok.LeadingTrivia = strings.Clone(tok.LeadingTrivia)
+		tok.TrailingTrivia = strings.Clone(tok.TrailingTrivia)
+		if tok.Raw == tok.Literal {
+			tok.Raw = tok.Literal
+		} else {
+			tok.Raw = strings.Clone(tok.Raw)
+		}
+	}
+	l.triviaStart = end
+}
+
+// trailingTriviaEnd returns the position just after the trailing trivia
+// starting at position. It scans the input without moving the lexer,
+// which lexes the same text again as part of the next token's trivia.
+func (l *Lexer) trailingTriviaEnd(position int) int {
+	for {
+		switch ch := l.byteAt(position); {
+		case ch == ' ' || ch == '\t':
+			position++
+		case ch == '\n':
+			return position + 1
+		case ch == '\r':
+			if l.byteAt(position+1) == '\n' {
+				return position + 2
+			}
+			return position + 1
+		case ch == '/' && l.byteAt(position+1) == '/':
+			position += 2
+			for l.ensure(position) && !isNewline(l.byteAt(position)) {
+				position++
+			}
+		case ch == '/' && l.byteAt(position+1) == '*':
+			end := position + 2
+			for !(l.byteAt(end) == '*' && l.byteAt(end+1) == '/') {
+				if !l.ensure(end) || isNewline(l.byteAt(end)) {
+					// a comment continuing past this line leads the next token
+					return position
+				}
+				end++
+			}
+			position = end + 2
+		default:
+			return position
+		}
+	}
+}
+
+// byteAt returns the input byte at position, or 0 past the end.
+func (l *Lexer) byteAt(position int) byte {
+	if !l.ensure(position) {
+		return 0
+	}
+	return l.input[position-l.base]
+}
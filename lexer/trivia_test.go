@@ -0,0 +1,101 @@
+package lexer
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/hculpan/htc/examples"
+)
+
+func TestTrivia(t *testing.T) {
+	input := "// header\nint x; // x\r\n\n  /* a\nb */ y /* c */ = 1;\n"
+
+	expected := []struct {
+		typ      TokenType
+		leading  string
+		trailing string
+	}{
+		{INT_TYPE, "// header\n", " "},
+		{IDENT, "", ""},
+		{SEMICOLON, "", " // x\r\n"},
+		{IDENT, "\n  /* a\nb */ ", " /* c */ "},
+		{ASSIGN, "", " "},
+		{INT, "", ""},
+		{SEMICOLON, "", "\n"},
+		{EOF, "", ""},
+	}
+
+	l := NewLexer(input)
+	l.SetTrivia(true)
+	tokens := l.Tokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %+v", len(expected), tokens)
+	}
+	for idx, tok := range tokens {
+		e := expected[idx]
+		if tok.Type != e.typ || tok.LeadingTrivia != e.leading || tok.TrailingTrivia != e.trailing {
+			t.Errorf("token %d: expected %s with trivia %q %q, got %s with %q %q",
+				idx, e.typ, e.leading, e.trailing, tok.Type, tok.LeadingTrivia, tok.TrailingTrivia)
+		}
+	}
+}
+
+func TestTriviaCoversInput(t *testing.T) {
+	inputs := []string{
+		"\xef\xbb\xbf  int x;\t// c\n",
+		"a /* open\nb",
+		"a /* one */ /* two\n */ b\r",
+		"\"s\\n\" /**/\r\n",
+		"",
+	}
+	for _, ex := range examples.All() {
+		inputs = append(inputs, ex.Source)
+	}
+
+	for _, input := range inputs {
+		lexers := map[string]*Lexer{
+			"string": NewLexer(input),
+			"reader": NewLexerFromReader(iotest.OneByteReader(strings.NewReader(input))),
+		}
+		for name, l := range lexers {
+			l.SetTrivia(true)
+			var sb strings.Builder
+			for _, tok := range l.Tokens() {
+				sb.WriteString(tok.LeadingTrivia)
+				sb.WriteString(input[tok.StartOffset:tok.EndOffset])
+				sb.WriteString(tok.TrailingTrivia)
+			}
+			if expected := strings.TrimPrefix(input, utf8BOM); sb.String() != expected {
+				t.Errorf("%s: expected %q, got %q", name, expected, sb.String())
+			}
+		}
+	}
+}
+
+func TestUpdateKeepsTrivia(t *testing.T) {
+	input := "int x; // x\n/* doc\n */ int y;\n"
+	edits := []textEdit{{11, 11, " more"}, {12, 12, "\n"}, {6, 6, "  "}, {20, 24, ""}}
+
+	l := NewLexer(input)
+	l.SetTrivia(true)
+	text := input
+	for _, e := range edits {
+		l.Update(Range{Start: e.start, End: e.end}, e.text)
+		tokens := l.DocumentTokens()
+		text = text[:e.start] + e.text + text[e.end:]
+
+		full := NewLexer(text)
+		full.SetTrivia(true)
+		expected := full.Tokens()
+		if len(tokens) != len(expected) {
+			t.Errorf("%q: expected %d tokens, got %d", text, len(expected), len(tokens))
+			continue
+		}
+		for idx, tok := range tokens {
+			if tok != expected[idx] {
+				t.Errorf("%q: token %d: expected %+v, got %+v", text, idx, expected[idx], tok)
+			}
+		}
+	}
+}
@@ -1,7 +1,158 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hculpan/htc/examples"
+)
+
+// command describes one node of the htc command tree. The same
+// definition drives dispatch, usage output, and shell completion.
+type command struct {
+	name        string
+	args        string
+	summary     string
+	subcommands []*command
+	// values returns the candidates for the command's positional
+	// argument, used for shell completion.
+	values func() []string
+	run    func(args []string) int
+}
+
+// root is the top of the command tree. It is assigned in init because the
+// completion command walks the tree it is part of.
+var root *command
+
+func init() {
+	root = &command{
+		name: "htc",
+		subcommands: []*command{
+			{
+				name:    "examples",
+				summary: "work with the embedded example programs",
+				subcommands: []*command{
+					{
+						name:    "list",
+						summary: "list the embedded example programs",
+						run:     examplesList,
+					},
+					{
+						name:    "show",
+						args:    "NAME",
+						summary: "print the source of an example",
+						values:  examples.Names,
+						run:     examplesShow,
+					},
+				},
+			},
+			{
+				name:    "completion",
+				args:    "SHELL",
+				summary: "generate a shell completion script",
+				values:  func() []string { return shellNames },
+				run:     completionCommand,
+			},
+			{
+				name:    "stats",
+				summary: "manage local usage statistics",
+				subcommands: []*command{
+					{
+						name:    "enable",
+						summary: "start recording local usage statistics",
+						run:     statsEnable,
+					},
+					{
+						name:    "disable",
+						summary: "stop recording and delete usage statistics",
+						run:     statsDisable,
+					},
+					{
+						name:    "show",
+						summary: "print the recorded usage statistics",
+						run:     statsShow,
+					},
+				},
+			},
+		},
+	}
+}
 
 func main() {
-	fmt.Printf("Hello!\n")
+	start := time.Now()
+	code := dispatch(root, os.Args[1:])
+	if cmdpath := commandPath(os.Args[1:]); cmdpath != "" {
+		recordUsage(cmdpath, code, time.Since(start))
+	}
+	os.Exit(code)
+}
+
+// dispatch walks the command tree following args and runs the command
+// it ends up at.
+func dispatch(cmd *command, args []string) int {
+	if len(cmd.subcommands) == 0 {
+		return cmd.run(args)
+	}
+	if len(args) > 0 {
+		if sub := cmd.lookup(args[0]); sub != nil {
+			return dispatch(sub, args[1:])
+		}
+	}
+	usage(os.Stderr)
+	return 2
+}
+
+func (c *command) lookup(name string) *command {
+	for _, sub := range c.subcommands {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: htc <command> [arguments]\n\n")
+	fmt.Fprintf(w, "commands:\n")
+	var walk func(cmd *command, path []string)
+	walk = func(cmd *command, path []string) {
+		if cmd.run != nil {
+			line := strings.Join(append(path, cmd.args), " ")
+			fmt.Fprintf(w, "  %-28s %s\n", strings.TrimSpace(line), cmd.summary)
+		}
+		for _, sub := range cmd.subcommands {
+			walk(sub, append(path, sub.name))
+		}
+	}
+	for _, sub := range root.subcommands {
+		walk(sub, []string{sub.name})
+	}
+}
+
+func examplesList(args []string) int {
+	if len(args) != 0 {
+		usage(os.Stderr)
+		return 2
+	}
+	for _, name := range examples.Names() {
+		fmt.Println(name)
+	}
+	return 0
+}
+
+func examplesShow(args []string) int {
+	if len(args) != 1 {
+		usage(os.Stderr)
+		return 2
+	}
+	ex, err := examples.Get(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", args[0], err)
+		return 1
+	}
+	fmt.Print(ex.Source)
+	return 0
 }
@@ -0,0 +1,350 @@
+package preprocessor
+
+import (
+	"fmt"
+
+	"github.com/hculpan/htc/lexer"
+)
+
+// condition is an open #if, #ifdef, or #ifndef group.
+type condition struct {
+	directive    lexer.Token // the directive name that opened the group
+	parentActive bool        // the enclosing text is being kept
+	active       bool        // the current branch is being kept
+	taken        bool        // some branch of the group has been kept
+	elseSeen     bool
+}
+
+// active reports whether text at the top of conds is kept.
+func active(conds []*condition) bool {
+	return len(conds) == 0 || conds[len(conds)-1].active
+}
+
+// isConditional reports whether name is a conditional directive, which
+// is processed even inside skipped text.
+func isConditional(name string) bool {
+	switch name {
+	case "if", "ifdef", "ifndef", "elif", "else", "endif":
+		return true
+	}
+	return false
+}
+
+// conditional executes the conditional directive whose name is args[0]
+// and returns the updated stack of open groups. Conditions inside
+// skipped text are not evaluated.
+func (p *Preprocessor) conditional(file string, conds []*condition, args []lexer.Token) []*condition {
+	directive, operands := args[0], args[1:]
+	var top *condition
+	if len(conds) > 0 {
+		top = conds[len(conds)-1]
+	}
+
+	switch directive.Literal {
+	case "if", "ifdef", "ifndef":
+		parent := active(conds)
+		value := false
+		if parent {
+			value = p.condition(file, directive, operands)
+		}
+		return append(conds, &condition{directive: directive, parentActive: parent, active: parent && value, taken: value})
+	case "elif":
+		if top == nil {
+			p.addErrorAt(file, directive, "#elif without #if")
+		} else if top.elseSeen {
+			p.addErrorAt(file, directive, "#elif after #else")
+		} else if top.parentActive && !top.taken {
+			top.active = p.condition(file, directive, operands)
+			top.taken = top.active
+		} else {
+			top.active = false
+		}
+	case "else":
+		if top == nil {
+			p.addErrorAt(file, directive, "#else without #if")
+		} else if top.elseSeen {
+			p.addErrorAt(file, directive, "#else after #else")
+		} else {
+			top.elseSeen = true
+			top.active = top.parentActive && !top.taken
+			top.taken = true
+		}
+		p.noOperands(file, directive, operands)
+	case "endif":
+		if top == nil {
+			p.addErrorAt(file, directive, "#endif without #if")
+		} else {
+			conds = conds[:len(conds)-1]
+		}
+		p.noOperands(file, directive, operands)
+	}
+	return conds
+}
+
+// condition evaluates the condition of the #if, #ifdef, #ifndef, or
+// #elif directive at directive.
+func (p *Preprocessor) condition(file string, directive lexer.Token, operands []lexer.Token) bool {
+	if directive.Literal == "if" || directive.Literal == "elif" {
+		value, ok := p.evaluate(file, directive, operands)
+		return ok && value != 0
+	}
+
+	if len(operands) == 0 || !isName(operands[0]) {
+		p.addErrorAt(file, directive, fmt.Sprintf("#%s expects a macro name", directive.Literal))
+		return false
+	}
+	p.noOperands(file, directive, operands[1:])
+	_, defined := p.macros[operands[0].Literal]
+	return defined == (directive.Literal == "ifdef")
+}
+
+// noOperands reports any operands of a directive that takes none.
+func (p *Preprocessor) noOperands(file string, directive lexer.Token, operands []lexer.Token) {
+	if len(operands) > 0 {
+		p.addErrorAt(file, operands[0], fmt.Sprintf("extra tokens after #%s", directive.Literal))
+	}
+}
+
+// evaluate returns the value of the constant expression in operands.
+// defined NAME and defined(NAME) are replaced first, then macros are
+// expanded and any identifiers left count as 0.
+func (p *Preprocessor) evaluate(file string, directive lexer.Token, operands []lexer.Token) (int64, bool) {
+	pending := []hiddenToken{}
+	for idx := 0; idx < len(operands); idx++ {
+		tok := operands[idx]
+		if tok.Type != lexer.IDENT || tok.Literal != "defined" {
+			pending = append(pending, hiddenToken{tok: tok})
+			continue
+		}
+
+		name, end := idx+1, idx+2
+		if name < len(operands) && operands[name].Type == lexer.LPAREN {
+			name, end = idx+2, idx+4
+			if end > len(operands) || operands[end-1].Type != lexer.RPAREN {
+				end = -1
+			}
+		}
+		if end < 0 || name >= len(operands) || !isName(operands[name]) {
+			p.addErrorAt(file, tok, "defined expects a macro name")
+			return 0, false
+		}
+		value := "0"
+		if _, ok := p.macros[operands[name].Literal]; ok {
+			value = "1"
+		}
+		pending = append(pending, hiddenToken{tok: lexer.Token{Type: lexer.INT, Literal: value, Base: 10, Line: tok.Line, Position: tok.Position}})
+		idx = end - 1
+	}
+
+	tokens := []lexer.Token{}
+	for _, ht := range p.expandHidden(file, pending) {
+		tokens = append(tokens, ht.tok)
+	}
+	if len(tokens) == 0 {
+		p.addErrorAt(file, directive, fmt.Sprintf("#%s with no expression", directive.Literal))
+		return 0, false
+	}
+
+	e := &evaluator{p: p, file: file, tokens: tokens, end: directive}
+	value := e.expression(true)
+	if !e.failed && e.pos < len(e.tokens) {
+		e.fail(e.tokens[e.pos], fmt.Sprintf("unexpected '%s' in #%s expression", e.tokens[e.pos].Literal, directive.Literal))
+	}
+	return value, !e.failed
+}
+
+// evaluator evaluates a preprocessor constant expression with C's
+// operators and precedence over int64 values. Operands that are not
+// evaluated, such as the right side of 0 && x, are parsed but cannot
+// fail.
+type evaluator struct {
+	p      *Preprocessor
+	file   string
+	tokens []lexer.Token
+	pos    int
+	end    lexer.Token // the directive, for errors at the end of the line
+	failed bool
+}
+
+// binaryPrecedence gives the precedence of each binary operator; higher
+// binds tighter.
+var binaryPrecedence = map[lexer.TokenType]int{
+	lexer.OR:        1,
+	lexer.AND:       2,
+	lexer.PIPE:      3,
+	lexer.CARET:     4,
+	lexer.AMPERSAND: 5,
+	lexer.EQ:        6,
+	lexer.NEQ:       6,
+	lexer.LT:        7,
+	lexer.GT:        7,
+	lexer.LE:        7,
+	lexer.GE:        7,
+	lexer.LSHIFT:    8,
+	lexer.RSHIFT:    8,
+	lexer.PLUS:      9,
+	lexer.MINUS:     9,
+	lexer.ASTERISK:  10,
+	lexer.SLASH:     10,
+	lexer.PERCENT:   10,
+}
+
+func (e *evaluator) fail(tok lexer.Token, msg string) {
+	if !e.failed {
+		e.p.addErrorAt(e.file, tok, msg)
+		e.failed = true
+	}
+}
+
+// next returns the next token, or false after reporting the unexpected
+// end of the expression.
+func (e *evaluator) next() (lexer.Token, bool) {
+	if e.pos >= len(e.tokens) {
+		e.fail(e.end, fmt.Sprintf("unexpected end of #%s expression", e.end.Literal))
+		return lexer.Token{}, false
+	}
+	e.pos++
+	return e.tokens[e.pos-1], true
+}
+
+func (e *evaluator) peek() lexer.TokenType {
+	if e.pos >= len(e.tokens) {
+		return lexer.EOF
+	}
+	return e.tokens[e.pos].Type
+}
+
+// expression evaluates a conditional expression, the lowest precedence
+// level.
+func (e *evaluator) expression(eval bool) int64 {
+	cond := e.binary(1, eval)
+	if e.failed || e.peek() != lexer.QUESTION {
+		return cond
+	}
+	e.pos++
+	a := e.expression(eval && cond != 0)
+	if tok, ok := e.next(); ok && tok.Type != lexer.COLON {
+		e.fail(tok, fmt.Sprintf("expected ':' in #%s expression", e.end.Literal))
+	}
+	b := e.expression(eval && cond == 0)
+	if cond != 0 {
+		return a
+	}
+	return b
+}
+
+// binary evaluates operators of at least precedence minPrecedence.
+func (e *evaluator) binary(minPrecedence int, eval bool) int64 {
+	left := e.unary(eval)
+	for !e.failed {
+		precedence := binaryPrecedence[e.peek()]
+		if precedence == 0 || precedence < minPrecedence {
+			break
+		}
+		op := e.tokens[e.pos]
+		e.pos++
+		evalRight := eval && !(op.Type == lexer.AND && left == 0) && !(op.Type == lexer.OR && left != 0)
+		right := e.binary(precedence+1, evalRight)
+		left = e.apply(op, left, right, evalRight)
+	}
+	return left
+}
+
+func (e *evaluator) apply(op lexer.Token, left int64, right int64, eval bool) int64 {
+	switch op.Type {
+	case lexer.OR:
+		return truth(left != 0 || right != 0)
+	case lexer.AND:
+		return truth(left != 0 && right != 0)
+	case lexer.PIPE:
+		return left | right
+	case lexer.CARET:
+		return left ^ right
+	case lexer.AMPERSAND:
+		return left & right
+	case lexer.EQ:
+		return truth(left == right)
+	case lexer.NEQ:
+		return truth(left != right)
+	case lexer.LT:
+		return truth(left < right)
+	case lexer.GT:
+		return truth(left > right)
+	case lexer.LE:
+		return truth(left <= right)
+	case lexer.GE:
+		return truth(left >= right)
+	case lexer.LSHIFT, lexer.RSHIFT:
+		if right < 0 {
+			if eval {
+				e.fail(op, fmt.Sprintf("negative shift count in #%s expression", e.end.Literal))
+			}
+			return 0
+		}
+		if op.Type == lexer.LSHIFT {
+			return left << right
+		}
+		return left >> right
+	case lexer.PLUS:
+		return left + right
+	case lexer.MINUS:
+		return left - right
+	case lexer.ASTERISK:
+		return left * right
+	default: // SLASH, PERCENT
+		if right == 0 {
+			if eval {
+				e.fail(op, fmt.Sprintf("division by zero in #%s expression", e.end.Literal))
+			}
+			return 0
+		}
+		if op.Type == lexer.SLASH {
+			return left / right
+		}
+		return left % right
+	}
+}
+
+// unary evaluates a unary operator, parenthesized expression, integer,
+// or identifier.
+func (e *evaluator) unary(eval bool) int64 {
+	tok, ok := e.next()
+	if !ok {
+		return 0
+	}
+	switch {
+	case tok.Type == lexer.PLUS:
+		return e.unary(eval)
+	case tok.Type == lexer.MINUS:
+		return -e.unary(eval)
+	case tok.Type == lexer.BANG:
+		return truth(e.unary(eval) == 0)
+	case tok.Type == lexer.TILDE:
+		return ^e.unary(eval)
+	case tok.Type == lexer.LPAREN:
+		value := e.expression(eval)
+		if next, ok := e.next(); ok && next.Type != lexer.RPAREN {
+			e.fail(next, fmt.Sprintf("missing ')' in #%s expression", e.end.Literal))
+		}
+		return value
+	case tok.Type == lexer.INT:
+		value, err := tok.IntValue()
+		if err != nil {
+			e.fail(tok, fmt.Sprintf("integer %s out of range in #%s expression", tok.Literal, e.end.Literal))
+		}
+		return value
+	case isName(tok):
+		// identifiers that are not macros evaluate to 0
+		return 0
+	default:
+		e.fail(tok, fmt.Sprintf("unexpected '%s' in #%s expression", tok.Literal, e.end.Literal))
+		return 0
+	}
+}
+
+func truth(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package preprocessor
+
+import (
+	"testing"
+
+	"github.com/hculpan/htc/source"
+)
+
+func TestConditionals(t *testing.T) {
+	tests := map[string]string{
+		"#ifdef A\na\n#else\nb\n#endif":                                      "b ",
+		"#define A\n#ifdef A\na\n#else\nb\n#endif":                           "a ",
+		"#ifndef A\na\n#endif\nc":                                            "a c ",
+		"#if 0\na\n#elif 2 > 1\nb\n#elif 1\nc\n#else\nd\n#endif":             "b ",
+		"#if 0\n#if 1\na\n#else\nb\n#endif\n#else\nc\n#endif":                "c ",
+		"#if 1\n#if 0\na\n#elif 1\nb\n#endif\n#endif":                        "b ",
+		"#define V 3\n#if V * 2 == 6 && defined(V) && !defined W\nv\n#endif": "v ",
+		"#define F(x) (x + 1)\n#if F(1) == 2 ? 0x10 >> 4 : 0\nf\n#endif":     "f ",
+		"#if UNDEFINED == 0 && -1 < 0 && ~0 == -1 && 7 % 4 == 3\nu\n#endif":  "u ",
+		"#if 0 && 1 / 0\nx\n#elif 1 || 1 % 0\ny\n#endif":                     "y ",
+		"#if (1 | 2) ^ 1 & 3 != 3\nz\n#endif":                                "z ",
+		"#if 0\n#define HIDDEN\n#endif\n#ifdef HIDDEN\nh\n#endif":            "",
+	}
+
+	for input, expected := range tests {
+		p := New(source.NewMapLoader(map[string]string{"main.c": input}))
+		if result := literals(p.Process("main.c")); result != expected {
+			t.Errorf("%q: expected %q, got %q", input, expected, result)
+		}
+		expectErrors(t, p)
+	}
+}
+
+func TestSkippedTextIgnoresDirectives(t *testing.T) {
+	p := New(source.NewMapLoader(map[string]string{
+		"main.c": "#if 0\n#bogus\n#include \"missing.h\"\nx\n#endif\ny",
+	}))
+	if result := literals(p.Process("main.c")); result != "y " {
+		t.Errorf("expected 'y ', got %q", result)
+	}
+	expectErrors(t, p)
+}
+
+func TestConditionalErrors(t *testing.T) {
+	tests := map[string]string{
+		"#if 1\nx":                         "main.c:1:2: unterminated #if",
+		"#endif":                           "main.c:1:2: #endif without #if",
+		"#else":                            "main.c:1:2: #else without #if",
+		"#elif 1":                          "main.c:1:2: #elif without #if",
+		"#if 1\n#else\n#else\n#endif":      "main.c:3:2: #else after #else",
+		"#if 1\n#else\n#elif 1\n#endif":    "main.c:3:2: #elif after #else",
+		"#if 1\n#endif x":                  "main.c:2:8: extra tokens after #endif",
+		"#ifdef\n#endif":                   "main.c:1:2: #ifdef expects a macro name",
+		"#ifdef A B\n#endif":               "main.c:1:10: extra tokens after #ifdef",
+		"#if\n#endif":                      "main.c:1:2: #if with no expression",
+		"#if 1 +\n#endif":                  "main.c:1:2: unexpected end of #if expression",
+		"#if (1\n#endif":                   "main.c:1:2: unexpected end of #if expression",
+		"#if (1 2)\n#endif":                "main.c:1:8: missing ')' in #if expression",
+		"#if 1 2\n#endif":                  "main.c:1:7: unexpected '2' in #if expression",
+		"#if 1 / 0\n#endif":                "main.c:1:7: division by zero in #if expression",
+		"#if 1 << -1\n#endif":              "main.c:1:7: negative shift count in #if expression",
+		"#if 1 ? 2\n#endif":                "main.c:1:2: unexpected end of #if expression",
+		"#if \"s\"\n#endif":                "main.c:1:5: unexpected 's' in #if expression",
+		"#if defined(\n#endif":             "main.c:1:5: defined expects a macro name",
+		"#if 99999999999999999999\n#endif": "main.c:1:5: integer 99999999999999999999 out of range in #if expression",
+	}
+
+	for input, expected := range tests {
+		p := New(source.NewMapLoader(map[string]string{"main.c": input}))
+		p.Process("main.c")
+		expectErrors(t, p, expected)
+	}
+}
+
+func TestDefine(t *testing.T) {
+	p := New(source.NewMapLoader(map[string]string{
+		"main.c": "#ifdef DEBUG\ndebug\n#endif\nLEVEL SQR(3)",
+	}))
+	p.Define("DEBUG")
+	p.Define("LEVEL=2")
+	p.Define("SQR(x)=x*x")
+	if result := literals(p.Process("main.c")); result != "debug 2 3 * 3 " {
+		t.Errorf("expected 'debug 2 3 * 3 ', got %q", result)
+	}
+	expectErrors(t, p)
+
+	p.Define("=1")
+	p.Define("LEVEL=3")
+	expectErrors(t, p, "<command line>:1:1: #define expects a macro name")
+	expectWarnings(t, p, "<command line>:1:1: macro LEVEL redefined; previous definition at <command line>:1:1")
+}
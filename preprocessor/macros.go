@@ -0,0 +1,267 @@
+package preprocessor
+
+import (
+	"fmt"
+
+	"github.com/hculpan/htc/lexer"
+)
+
+// macro is a #define'd name and the tokens it expands to. Function-like
+// macros have params, which may be empty; object-like macros have nil
+// params.
+type macro struct {
+	params []string
+	body   []lexer.Token
+	file   string
+	tok    lexer.Token // the name in the #define
+}
+
+// define records the macro defined by the #define directive at
+// directive, whose operands are args. The macro is function-like if a
+// '(' follows its name without intervening space.
+func (p *Preprocessor) define(file string, directive lexer.Token, args []lexer.Token) {
+	if len(args) == 0 || !isName(args[0]) {
+		p.addErrorAt(file, directive, "#define expects a macro name")
+		return
+	}
+	name := args[0]
+	m := &macro{body: args[1:], file: file, tok: name}
+	if len(args) > 1 && args[1].Type == lexer.LPAREN && args[1].StartOffset == name.EndOffset {
+		params, rest, ok := p.parseParams(file, name, args[2:])
+		if !ok {
+			return
+		}
+		m.params, m.body = params, rest
+	}
+
+	if previous, ok := p.macros[name.Literal]; ok && !previous.sameAs(m) {
+		p.addWarningAt(file, name, fmt.Sprintf("macro %s redefined; previous definition at %s:%d:%d",
+			name.Literal, previous.file, previous.tok.Line, previous.tok.Position))
+	}
+	p.macros[name.Literal] = m
+}
+
+// parseParams parses the parameter list of the function-like macro name
+// from args, which follow the opening '('. It returns the parameter
+// names and the tokens after the closing ')'.
+func (p *Preprocessor) parseParams(file string, name lexer.Token, args []lexer.Token) ([]string, []lexer.Token, bool) {
+	params := []string{}
+	if len(args) > 0 && args[0].Type == lexer.RPAREN {
+		return params, args[1:], true
+	}
+	for idx := 0; idx < len(args); idx += 2 {
+		if !isName(args[idx]) {
+			p.addErrorAt(file, args[idx], fmt.Sprintf("expected parameter name in macro %s", name.Literal))
+			return nil, nil, false
+		}
+		for _, param := range params {
+			if param == args[idx].Literal {
+				p.addErrorAt(file, args[idx], fmt.Sprintf("duplicate parameter %s in macro %s", param, name.Literal))
+				return nil, nil, false
+			}
+		}
+		params = append(params, args[idx].Literal)
+
+		if idx+1 < len(args) && args[idx+1].Type == lexer.RPAREN {
+			return params, args[idx+2:], true
+		} else if idx+1 >= len(args) || args[idx+1].Type != lexer.COMMA {
+			break
+		}
+	}
+	p.addErrorAt(file, name, fmt.Sprintf("missing ')' in parameter list of macro %s", name.Literal))
+	return nil, nil, false
+}
+
+// undef removes the macro named by the #undef directive at directive.
+// Removing a name that is not defined is allowed.
+func (p *Preprocessor) undef(file string, directive lexer.Token, args []lexer.Token) {
+	if len(args) == 0 || !isName(args[0]) {
+		p.addErrorAt(file, directive, "#undef expects a macro name")
+		return
+	}
+	if len(args) > 1 {
+		p.addErrorAt(file, args[1], "extra tokens after #undef")
+	}
+	delete(p.macros, args[0].Literal)
+}
+
+// hiddenToken is a token during macro expansion together with the names
+// of the macros whose expansion produced it. A token is never expanded
+// by a macro in its hide set, which stops recursive macros.
+type hiddenToken struct {
+	tok  lexer.Token
+	hide map[string]bool
+}
+
+// expand returns tokens, read from file, with all macros expanded.
+func (p *Preprocessor) expand(file string, tokens []lexer.Token) []Token {
+	pending := make([]hiddenToken, len(tokens))
+	for idx, tok := range tokens {
+		pending[idx] = hiddenToken{tok: tok}
+	}
+
+	result := []Token{}
+	for _, ht := range p.expandHidden(file, pending) {
+		ht.tok.File = file
+		result = append(result, ht.tok)
+	}
+	return result
+}
+
+// expandHidden expands the macros in pending. Each expansion replaces
+// the macro use and is rescanned together with the tokens after it, so
+// an expansion ending in a function-like macro name can take its
+// arguments from the source. Expanded tokens take the position of the
+// macro use.
+func (p *Preprocessor) expandHidden(file string, pending []hiddenToken) []hiddenToken {
+	result := []hiddenToken{}
+	for len(pending) > 0 {
+		ht := pending[0]
+		m, ok := p.macros[ht.tok.Literal]
+		if !ok || !isName(ht.tok) || ht.hide[ht.tok.Literal] {
+			result = append(result, ht)
+			pending = pending[1:]
+			continue
+		}
+
+		rest := pending[1:]
+		var args [][]hiddenToken
+		if m.params != nil {
+			if len(rest) == 0 || rest[0].tok.Type != lexer.LPAREN {
+				// a function-like macro name without arguments is left alone
+				result = append(result, ht)
+				pending = rest
+				continue
+			}
+			var ok bool
+			args, rest, ok = p.collectArgs(file, ht.tok, m, rest)
+			if !ok {
+				pending = rest
+				continue
+			}
+			for idx, arg := range args {
+				args[idx] = p.expandHidden(file, arg)
+			}
+		}
+
+		hide := map[string]bool{ht.tok.Literal: true}
+		for name := range ht.hide {
+			hide[name] = true
+		}
+		pending = append(m.substitute(args, ht.tok, hide), rest...)
+	}
+	return result
+}
+
+// collectArgs reads the arguments of a use of the function-like macro m
+// at site from pending, which starts with the '('. It returns the
+// arguments and the tokens after the closing ')'.
+func (p *Preprocessor) collectArgs(file string, site lexer.Token, m *macro, pending []hiddenToken) ([][]hiddenToken, []hiddenToken, bool) {
+	args := [][]hiddenToken{{}}
+	depth := 0
+	for idx, ht := range pending {
+		switch {
+		case ht.tok.Type == lexer.LPAREN:
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ht.tok.Type == lexer.RPAREN:
+			depth--
+			if depth == 0 {
+				if len(m.params) == 0 && len(args) == 1 && len(args[0]) == 0 {
+					args = nil
+				}
+				if len(args) != len(m.params) {
+					p.addErrorAt(file, site, fmt.Sprintf("macro %s expects %s, got %d", site.Literal, plural(len(m.params), "argument"), len(args)))
+					return nil, pending[idx+1:], false
+				}
+				return args, pending[idx+1:], true
+			}
+		case ht.tok.Type == lexer.COMMA && depth == 1:
+			args = append(args, []hiddenToken{})
+			continue
+		case ht.tok.Type == lexer.EOF:
+			p.addErrorAt(file, site, fmt.Sprintf("unterminated argument list for macro %s", site.Literal))
+			return nil, pending[idx:], false
+		}
+		args[len(args)-1] = append(args[len(args)-1], ht)
+	}
+	p.addErrorAt(file, site, fmt.Sprintf("unterminated argument list for macro %s", site.Literal))
+	return nil, nil, false
+}
+
+// substitute returns the body of m with parameters replaced by args. The
+// body tokens are placed at site and hidden from the macros in hide.
+func (m *macro) substitute(args [][]hiddenToken, site lexer.Token, hide map[string]bool) []hiddenToken {
+	result := []hiddenToken{}
+	for _, tok := range m.body {
+		if idx := m.param(tok); idx >= 0 {
+			for _, arg := range args[idx] {
+				argHide := map[string]bool{}
+				for name := range arg.hide {
+					argHide[name] = true
+				}
+				for name := range hide {
+					argHide[name] = true
+				}
+				result = append(result, hiddenToken{tok: arg.tok, hide: argHide})
+			}
+			continue
+		}
+		tok.Line, tok.Position, tok.EndLine, tok.EndColumn = site.Line, site.Position, site.EndLine, site.EndColumn
+		tok.StartOffset, tok.EndOffset = site.StartOffset, site.EndOffset
+		result = append(result, hiddenToken{tok: tok, hide: hide})
+	}
+	return result
+}
+
+// param returns the index of the parameter tok names, or -1.
+func (m *macro) param(tok lexer.Token) int {
+	if !isName(tok) {
+		return -1
+	}
+	for idx, param := range m.params {
+		if param == tok.Literal {
+			return idx
+		}
+	}
+	return -1
+}
+
+// plural returns n followed by noun, made plural unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// isName reports whether tok is an identifier or keyword, the tokens a
+// macro can be named by.
+func isName(tok lexer.Token) bool {
+	if tok.Type == lexer.IDENT {
+		return true
+	}
+	keyword, ok := lexer.LookupKeyword(tok.Literal)
+	return ok && keyword == tok.Type
+}
+
+// sameAs reports whether m and other have the same parameters and body,
+// in which case redefining the macro is harmless.
+func (m *macro) sameAs(other *macro) bool {
+	if (m.params == nil) != (other.params == nil) || len(m.params) != len(other.params) || len(m.body) != len(other.body) {
+		return false
+	}
+	for idx := range m.params {
+		if m.params[idx] != other.params[idx] {
+			return false
+		}
+	}
+	for idx := range m.body {
+		if m.body[idx].Type != other.body[idx].Type || m.body[idx].Literal != other.body[idx].Literal {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package preprocessor
+
+import (
+	"testing"
+
+	"github.com/hculpan/htc/lexer"
+	"github.com/hculpan/htc/source"
+)
+
+func expectWarnings(t *testing.T, p *Preprocessor, expected ...string) {
+	t.Helper()
+	if len(p.Warnings()) != len(expected) {
+		t.Fatalf("expected %d warnings, got %v", len(expected), p.Warnings())
+	}
+	for idx, err := range p.Warnings() {
+		if err.Error() != expected[idx] {
+			t.Errorf("warning expected '%s', got '%s'", expected[idx], err.Error())
+		}
+	}
+}
+
+func TestObjectLikeMacros(t *testing.T) {
+	loader := source.NewMapLoader(map[string]string{
+		"main.c":   "#include \"limits.h\"\n#define SIZE MAX + 1\nint a = SIZE; \"MAX\"\n#undef MAX\nMAX\n",
+		"limits.h": "#define MAX 100\n#define EMPTY\nEMPTY",
+	})
+
+	p := New(loader)
+	expected := []expectedToken{
+		{lexer.INT_TYPE, "int", "main.c", 3},
+		{lexer.IDENT, "a", "main.c", 3},
+		{lexer.ASSIGN, "=", "main.c", 3},
+		{lexer.INT, "100", "main.c", 3},
+		{lexer.PLUS, "+", "main.c", 3},
+		{lexer.INT, "1", "main.c", 3},
+		{lexer.SEMICOLON, ";", "main.c", 3},
+		{lexer.STRING, "MAX", "main.c", 3},
+		{lexer.IDENT, "MAX", "main.c", 5},
+		{lexer.EOF, "", "main.c", 6},
+	}
+	tokens := p.Process("main.c")
+	validateTokens(t, expected, tokens)
+	expectErrors(t, p)
+	expectWarnings(t, p)
+
+	// expanded tokens take the position of the macro use
+	if tokens[3].Position != 9 || tokens[5].Position != 9 {
+		t.Errorf("expected expansion at column 9, got %d and %d", tokens[3].Position, tokens[5].Position)
+	}
+}
+
+func TestSelfReferentialMacros(t *testing.T) {
+	loader := source.NewMapLoader(map[string]string{
+		"main.c": "#define x x + 1\n#define a b\n#define b a\nx a",
+	})
+
+	p := New(loader)
+	expected := []expectedToken{
+		{lexer.IDENT, "x", "main.c", 4},
+		{lexer.PLUS, "+", "main.c", 4},
+		{lexer.INT, "1", "main.c", 4},
+		{lexer.IDENT, "a", "main.c", 4},
+		{lexer.EOF, "", "main.c", 4},
+	}
+	validateTokens(t, expected, p.Process("main.c"))
+	expectErrors(t, p)
+}
+
+func TestMacroDirectiveErrors(t *testing.T) {
+	loader := source.NewMapLoader(map[string]string{
+		"main.c": "#define N 1\n#define N 1\n#define N 2\n#define\n#define 3 x\n#undef N x\n#undef\n#define int long\nint",
+	})
+
+	p := New(loader)
+	expected := []expectedToken{
+		{lexer.LONG, "long", "main.c", 9},
+		{lexer.EOF, "", "main.c", 9},
+	}
+	validateTokens(t, expected, p.Process("main.c"))
+	expectErrors(t, p,
+		"main.c:4:2: #define expects a macro name",
+		"main.c:5:2: #define expects a macro name",
+		"main.c:6:10: extra tokens after #undef",
+		"main.c:7:2: #undef expects a macro name",
+	)
+	expectWarnings(t, p, "main.c:3:9: macro N redefined; previous definition at main.c:2:9")
+}
+
+func literals(tokens []Token) string {
+	result := ""
+	for _, tok := range tokens {
+		if tok.Type != lexer.EOF {
+			result += tok.Literal + " "
+		}
+	}
+	return result
+}
+
+func TestFunctionLikeMacros(t *testing.T) {
+	tests := map[string]string{
+		"#define SQR(x) ((x)*(x))\nSQR(a + 1)":                       "( ( a + 1 ) * ( a + 1 ) ) ",
+		"#define MAX(a, b) ((a) > (b) ? (a) : (b))\nMAX(f(1, 2), 3)": "( ( f ( 1 , 2 ) ) > ( 3 ) ? ( f ( 1 , 2 ) ) : ( 3 ) ) ",
+		"#define F() 42\nF() F":                                      "42 F ",
+		"#define ID(x) x\nID()":                                      "",
+		"#define G (x)\nG":                                           "( x ) ",
+		"#define TWICE(x) x x\n#define N 7\nTWICE(N)":                "7 7 ",
+		"#define f(x) x + f(x)\nf(f(1))":                             "1 + f ( 1 ) + f ( 1 + f ( 1 ) ) ",
+		"#define ADD(a, b) a + b\n#define CALL ADD\nCALL(1,\n2)":     "1 + 2 ",
+		"#define SQR(x) x*x\nSQR (2)":                                "2 * 2 ",
+	}
+
+	for input, expected := range tests {
+		p := New(source.NewMapLoader(map[string]string{"main.c": input}))
+		if result := literals(p.Process("main.c")); result != expected {
+			t.Errorf("%q: expected %q, got %q", input, expected, result)
+		}
+		expectErrors(t, p)
+	}
+}
+
+func TestFunctionLikeMacroErrors(t *testing.T) {
+	tests := map[string]string{
+		"#define F(a, b) a\nF(1)":   "main.c:2:1: macro F expects 2 arguments, got 1",
+		"#define F(a) a\nF(1, 2) x": "main.c:2:1: macro F expects 1 argument, got 2",
+		"#define F(a) a\nF(1":       "main.c:2:1: unterminated argument list for macro F",
+		"#define F(a, 1) a\n":       "main.c:1:14: expected parameter name in macro F",
+		"#define F(a, a) a\n":       "main.c:1:14: duplicate parameter a in macro F",
+		"#define F(a\n":             "main.c:1:9: missing ')' in parameter list of macro F",
+		"#define F(a b) a\n":        "main.c:1:9: missing ')' in parameter list of macro F",
+	}
+
+	for input, expected := range tests {
+		p := New(source.NewMapLoader(map[string]string{"main.c": input}))
+		p.Process("main.c")
+		expectErrors(t, p, expected)
+	}
+}
+
+func TestFunctionLikeRedefinition(t *testing.T) {
+	p := New(source.NewMapLoader(map[string]string{
+		"main.c": "#define F(a) a\n#define F(a) a\n#define F(b) b\n#define F (a) a\n",
+	}))
+	p.Process("main.c")
+	expectErrors(t, p)
+	expectWarnings(t, p,
+		"main.c:3:9: macro F redefined; previous definition at main.c:2:9",
+		"main.c:4:9: macro F redefined; previous definition at main.c:3:9",
+	)
+}
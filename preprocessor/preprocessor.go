@@ -0,0 +1,205 @@
+// Package preprocessor runs the preprocessing stage between the lexer and
+// the parser. It executes directives such as #include and produces a
+// single token stream in which every token records the file it came from.
+package preprocessor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/hculpan/htc/lexer"
+	"github.com/hculpan/htc/source"
+)
+
+// commandLine is the file name used for macros defined through Define.
+const commandLine = "<command line>"
+
+// Token is a lexer token. Its File names the file it was read from, or
+// for tokens produced by a macro expansion, the file of the expansion.
+type Token = lexer.Token
+
+// Preprocessor expands the directives in a source file and the files it
+// includes.
+type Preprocessor struct {
+	loader   source.Loader
+	active   map[string]bool // files being included, to detect cycles
+	macros   map[string]*macro
+	errors   []error
+	warnings []error
+}
+
+// New returns a preprocessor that reads files through loader.
+func New(loader source.Loader) *Preprocessor {
+	return &Preprocessor{
+		loader:   loader,
+		active:   map[string]bool{},
+		macros:   map[string]*macro{},
+		errors:   []error{},
+		warnings: []error{},
+	}
+}
+
+// Process preprocesses the named file and returns its tokens, ending in
+// EOF. Comments are dropped. Problems are reported through Errors and
+// the offending directive is skipped.
+func (p *Preprocessor) Process(name string) []Token {
+	name = source.VirtualPath(name)
+	contents, err := p.loader.Load(name)
+	if err != nil {
+		p.errors = append(p.errors, err)
+		return []Token{{Type: lexer.EOF, Line: 1, Position: 1, EndLine: 1, EndColumn: 1, File: name}}
+	}
+	return p.file(name, contents, true)
+}
+
+func (p *Preprocessor) Errors() []error {
+	return p.errors
+}
+
+func (p *Preprocessor) HasErrors() bool {
+	return len(p.errors) != 0
+}
+
+// Warnings returns diagnostics about suspicious but valid input.
+func (p *Preprocessor) Warnings() []error {
+	return p.warnings
+}
+
+func (p *Preprocessor) addErrorAt(file string, tok lexer.Token, msg string) {
+	p.errors = append(p.errors, &lexer.Error{File: file, Line: tok.Line, Column: tok.Position, Msg: msg})
+}
+
+func (p *Preprocessor) addWarningAt(file string, tok lexer.Token, msg string) {
+	p.warnings = append(p.warnings, &lexer.Error{File: file, Line: tok.Line, Column: tok.Position, Msg: msg})
+}
+
+// file preprocesses contents, the text of the file name. The EOF token
+// is kept only for the main file.
+func (p *Preprocessor) file(name string, contents string, main bool) []Token {
+	p.active[name] = true
+	defer delete(p.active, name)
+
+	l := lexer.NewLexerWithOptions(contents, lexer.Options{Filename: name, SkipComments: true})
+	tokens := l.Tokens()
+	p.errors = append(p.errors, l.Errors()...)
+
+	// text collects the tokens between directives, which are expanded
+	// together so macro arguments can span lines
+	result, text := []Token{}, []lexer.Token{}
+	conds := []*condition{}
+	for idx := 0; idx < len(tokens); idx++ {
+		tok := tokens[idx]
+		if tok.Type == lexer.HASH && (idx == 0 || tokens[idx-1].EndLine < tok.Line) {
+			end := idx + 1
+			for end < len(tokens) && tokens[end].Type != lexer.EOF && tokens[end].Line == tok.Line {
+				end++
+			}
+			result = append(result, p.expand(name, text)...)
+			text = nil
+			args := tokens[idx+1 : end]
+			if len(args) > 0 && isConditional(args[0].Literal) {
+				conds = p.conditional(name, conds, args)
+			} else if active(conds) {
+				result = append(result, p.directive(name, tok, args)...)
+			}
+			idx = end - 1
+			continue
+		}
+		if tok.Type == lexer.EOF {
+			for _, cond := range conds {
+				p.addErrorAt(name, cond.directive, fmt.Sprintf("unterminated #%s", cond.directive.Literal))
+			}
+			if !main {
+				break
+			}
+		} else if !active(conds) {
+			continue
+		}
+		text = append(text, tok)
+	}
+	return append(result, p.expand(name, text)...)
+}
+
+// Define defines a macro the way the -D option of a C compiler does:
+// NAME defines NAME as 1 and NAME=VALUE defines it as VALUE. Problems
+// are reported as errors in the file "<command line>".
+func (p *Preprocessor) Define(definition string) {
+	name, value, found := strings.Cut(definition, "=")
+	if !found {
+		value = "1"
+	}
+	l := lexer.NewLexerWithOptions(name+" "+value, lexer.Options{Filename: commandLine})
+	tokens := l.Tokens()
+	p.errors = append(p.errors, l.Errors()...)
+	directive := lexer.Token{Type: lexer.IDENT, Literal: "define", Line: 1, Position: 1}
+	p.define(commandLine, directive, tokens[:len(tokens)-1])
+}
+
+// directive executes the directive introduced by hash, whose remaining
+// tokens on the line are args, and returns the tokens it expands to.
+func (p *Preprocessor) directive(file string, hash lexer.Token, args []lexer.Token) []Token {
+	if len(args) == 0 {
+		// a lone # is a null directive
+		return nil
+	}
+	switch args[0].Literal {
+	case "include":
+		return p.include(file, args[0], args[1:])
+	case "define":
+		p.define(file, args[0], args[1:])
+		return nil
+	case "undef":
+		p.undef(file, args[0], args[1:])
+		return nil
+	default:
+		p.addErrorAt(file, args[0], fmt.Sprintf("unknown directive #%s", args[0].Literal))
+		return nil
+	}
+}
+
+// include returns the tokens of the file named by args, the operands of
+// the #include directive at directive.
+func (p *Preprocessor) include(file string, directive lexer.Token, args []lexer.Token) []Token {
+	if len(args) == 0 || args[0].Type != lexer.STRING {
+		p.addErrorAt(file, directive, "#include expects \"FILENAME\"")
+		return nil
+	}
+	if len(args) > 1 {
+		p.addErrorAt(file, args[1], "extra tokens after #include")
+	}
+
+	name, contents, err := p.load(file, args[0].Literal)
+	if err != nil {
+		p.addErrorAt(file, args[0], err.Error())
+		return nil
+	}
+	if p.active[name] {
+		p.addErrorAt(file, args[0], fmt.Sprintf("recursive #include of %s", name))
+		return nil
+	}
+	return p.file(name, contents, false)
+}
+
+// load finds an included file. Names are resolved against the directory
+// of the including file first and then as given.
+func (p *Preprocessor) load(from string, name string) (string, string, error) {
+	candidates := []string{source.VirtualPath(path.Join(path.Dir(from), name))}
+	if plain := source.VirtualPath(name); plain != candidates[0] {
+		candidates = append(candidates, plain)
+	}
+
+	var firstErr error
+	for _, candidate := range candidates {
+		contents, err := p.loader.Load(candidate)
+		if err == nil {
+			return candidate, contents, nil
+		}
+		if firstErr == nil || !errors.Is(err, fs.ErrNotExist) {
+			firstErr = err
+		}
+	}
+	return "", "", firstErr
+}
@@ -0,0 +1,116 @@
+package preprocessor
+
+import (
+	"testing"
+
+	"github.com/hculpan/htc/lexer"
+	"github.com/hculpan/htc/source"
+)
+
+type expectedToken struct {
+	Type    lexer.TokenType
+	Literal string
+	File    string
+	Line    int
+}
+
+func validateTokens(t *testing.T, expected []expectedToken, tokens []Token) {
+	t.Helper()
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(expected), len(tokens), tokens)
+	}
+	for idx, tok := range tokens {
+		e := expected[idx]
+		if tok.Type != e.Type || tok.Literal != e.Literal || tok.File != e.File || tok.Line != e.Line {
+			t.Errorf("expected %s %q at %s:%d, got %s %q at %s:%d",
+				e.Type, e.Literal, e.File, e.Line, tok.Type, tok.Literal, tok.File, tok.Line)
+		}
+	}
+}
+
+func expectErrors(t *testing.T, p *Preprocessor, expected ...string) {
+	t.Helper()
+	if len(p.Errors()) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), p.Errors())
+	}
+	for idx, err := range p.Errors() {
+		if err.Error() != expected[idx] {
+			t.Errorf("error expected '%s', got '%s'", expected[idx], err.Error())
+		}
+	}
+}
+
+func TestInclude(t *testing.T) {
+	loader := source.NewMapLoader(map[string]string{
+		"main.c":        "#include \"inc/defs.h\"\nint main; // done\n",
+		"inc/defs.h":    "  # include \"types.h\" /* ok */\nint a;",
+		"inc/types.h":   "int t;\n#\n",
+		"unused/main.c": "x",
+	})
+
+	p := New(loader)
+	expected := []expectedToken{
+		{lexer.INT_TYPE, "int", "inc/types.h", 1},
+		{lexer.IDENT, "t", "inc/types.h", 1},
+		{lexer.SEMICOLON, ";", "inc/types.h", 1},
+		{lexer.INT_TYPE, "int", "inc/defs.h", 2},
+		{lexer.IDENT, "a", "inc/defs.h", 2},
+		{lexer.SEMICOLON, ";", "inc/defs.h", 2},
+		{lexer.INT_TYPE, "int", "main.c", 2},
+		{lexer.IDENT, "main", "main.c", 2},
+		{lexer.SEMICOLON, ";", "main.c", 2},
+		{lexer.EOF, "", "main.c", 3},
+	}
+	validateTokens(t, expected, p.Process("main.c"))
+	expectErrors(t, p)
+}
+
+func TestIncludeFallsBackToLoaderRoot(t *testing.T) {
+	loader := source.NewMapLoader(map[string]string{
+		"src/main.c": "#include \"common.h\"\nx",
+		"common.h":   "y",
+	})
+
+	p := New(loader)
+	expected := []expectedToken{
+		{lexer.IDENT, "y", "common.h", 1},
+		{lexer.IDENT, "x", "src/main.c", 2},
+		{lexer.EOF, "", "src/main.c", 2},
+	}
+	validateTokens(t, expected, p.Process(`src\main.c`))
+	expectErrors(t, p)
+}
+
+func TestIncludeErrors(t *testing.T) {
+	loader := source.NewMapLoader(map[string]string{
+		"main.c": "#include \"a.h\"\n#include \"missing.h\"\n#include <b.h>\n#include \"a.h\" x\n#pragma once\nx # y\n",
+		"a.h":    "#include \"main.c\"\n\"open",
+	})
+
+	p := New(loader)
+	expected := []expectedToken{
+		{lexer.STRING, "open", "a.h", 2},
+		{lexer.STRING, "open", "a.h", 2},
+		{lexer.IDENT, "x", "main.c", 6},
+		{lexer.HASH, "#", "main.c", 6},
+		{lexer.IDENT, "y", "main.c", 6},
+		{lexer.EOF, "", "main.c", 7},
+	}
+	validateTokens(t, expected, p.Process("main.c"))
+	expectErrors(t, p,
+		"a.h:2:1: non-terminated string",
+		"a.h:1:10: recursive #include of main.c",
+		"main.c:2:10: missing.h: file does not exist",
+		"main.c:3:2: #include expects \"FILENAME\"",
+		"main.c:4:16: extra tokens after #include",
+		"a.h:2:1: non-terminated string",
+		"a.h:1:10: recursive #include of main.c",
+		"main.c:5:2: unknown directive #pragma",
+	)
+}
+
+func TestProcessMissingFile(t *testing.T) {
+	p := New(source.NewMapLoader(nil))
+	validateTokens(t, []expectedToken{{lexer.EOF, "", "main.c", 1}}, p.Process("main.c"))
+	expectErrors(t, p, "main.c: file does not exist")
+}
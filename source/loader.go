@@ -0,0 +1,174 @@
+// Package source abstracts access to htc source files so the driver,
+// preprocessor, and editor integrations can read from disk, memory,
+// embedded files, or the network through the same interface.
+package source
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Loader loads the contents of a named source file.
+type Loader interface {
+	Load(name string) (string, error)
+}
+
+// notFound returns an error for name that matches fs.ErrNotExist.
+func notFound(name string) error {
+	return fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+}
+
+// DirLoader loads files from the operating system's file system. Relative
+// names are resolved against Root.
+type DirLoader struct {
+	Root string
+}
+
+// NewDirLoader returns a loader for files under root.
+func NewDirLoader(root string) *DirLoader {
+	return &DirLoader{Root: root}
+}
+
+func (d *DirLoader) Load(name string) (string, error) {
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(d.Root, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// MapLoader loads files from an in-memory map of names to contents.
+// Names are compared as virtual paths.
+type MapLoader struct {
+	files map[string]string
+}
+
+// NewMapLoader returns a loader serving files. The map is copied.
+func NewMapLoader(files map[string]string) *MapLoader {
+	m := &MapLoader{files: map[string]string{}}
+	for name, contents := range files {
+		m.files[VirtualPath(name)] = contents
+	}
+	return m
+}
+
+func (m *MapLoader) Load(name string) (string, error) {
+	name = VirtualPath(name)
+	contents, ok := m.files[name]
+	if !ok {
+		return "", notFound(name)
+	}
+	return contents, nil
+}
+
+// FSLoader loads files from an fs.FS such as an embed.FS.
+type FSLoader struct {
+	FS fs.FS
+}
+
+// NewFSLoader returns a loader for files in fsys.
+func NewFSLoader(fsys fs.FS) *FSLoader {
+	return &FSLoader{FS: fsys}
+}
+
+func (f *FSLoader) Load(name string) (string, error) {
+	data, err := fs.ReadFile(f.FS, VirtualPath(name))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// HTTPLoader loads files by fetching them relative to BaseURL.
+type HTTPLoader struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewHTTPLoader returns a loader fetching files below baseURL with
+// http.DefaultClient.
+func NewHTTPLoader(baseURL string) *HTTPLoader {
+	return &HTTPLoader{BaseURL: baseURL, Client: http.DefaultClient}
+}
+
+// Load fetches name below BaseURL. Names that are absolute or lead out of
+// BaseURL through "..", which url.JoinPath would otherwise resolve, are
+// rejected with an error matching fs.ErrInvalid.
+func (h *HTTPLoader) Load(name string) (string, error) {
+	rel := VirtualPath(name)
+	if path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%s: outside %s: %w", name, h.BaseURL, fs.ErrInvalid)
+	}
+	u, err := url.JoinPath(h.BaseURL, rel)
+	if err != nil {
+		return "", err
+	}
+	resp, err := h.Client.Get(u)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", notFound(name)
+	} else if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", name, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Overlay serves in-memory documents in front of another loader. Editors
+// use it to supply the contents of unsaved buffers.
+type Overlay struct {
+	base Loader
+
+	mu        sync.RWMutex
+	documents map[string]string
+}
+
+// NewOverlay returns an overlay with no documents in front of base.
+func NewOverlay(base Loader) *Overlay {
+	return &Overlay{base: base, documents: map[string]string{}}
+}
+
+// Set replaces the contents served for name. Names are compared as
+// virtual paths.
+func (o *Overlay) Set(name string, contents string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.documents[VirtualPath(name)] = contents
+}
+
+// Remove drops the overlay document for name so the base loader is used
+// again.
+func (o *Overlay) Remove(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.documents, VirtualPath(name))
+}
+
+func (o *Overlay) Load(name string) (string, error) {
+	o.mu.RLock()
+	contents, ok := o.documents[VirtualPath(name)]
+	o.mu.RUnlock()
+	if ok {
+		return contents, nil
+	}
+	return o.base.Load(name)
+}
@@ -0,0 +1,119 @@
+package source
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func expectLoad(t *testing.T, loader Loader, name string, expected string) {
+	t.Helper()
+	contents, err := loader.Load(name)
+	if err != nil {
+		t.Errorf("%s: unexpected error %s", name, err)
+	} else if contents != expected {
+		t.Errorf("%s: expected '%s', got '%s'", name, expected, contents)
+	}
+}
+
+func expectNotFound(t *testing.T, loader Loader, name string) {
+	t.Helper()
+	if _, err := loader.Load(name); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("%s: expected fs.ErrNotExist, got %v", name, err)
+	}
+}
+
+func TestDirLoader(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.c"), []byte("int main;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewDirLoader(dir)
+	expectLoad(t, loader, "main.c", "int main;")
+	expectLoad(t, loader, filepath.Join(dir, "main.c"), "int main;")
+	expectNotFound(t, loader, "missing.c")
+}
+
+func TestMapLoader(t *testing.T) {
+	files := map[string]string{"a.c": "int a;"}
+	loader := NewMapLoader(files)
+	files["a.c"] = "changed"
+
+	expectLoad(t, loader, "a.c", "int a;")
+	expectNotFound(t, loader, "b.c")
+}
+
+func TestFSLoader(t *testing.T) {
+	loader := NewFSLoader(fstest.MapFS{
+		"inc/defs.h": {Data: []byte("int x;")},
+	})
+
+	expectLoad(t, loader, "inc/defs.h", "int x;")
+	expectNotFound(t, loader, "defs.h")
+}
+
+func TestHTTPLoader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/src/main.c":
+			w.Write([]byte("int main;"))
+		case "/src/broken.c":
+			http.Error(w, "broken", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	loader := NewHTTPLoader(server.URL + "/src")
+	expectLoad(t, loader, "main.c", "int main;")
+	expectNotFound(t, loader, "missing.c")
+	if _, err := loader.Load("broken.c"); err == nil || errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected server error, got %v", err)
+	}
+}
+
+func TestHTTPLoaderStaysBelowBaseURL(t *testing.T) {
+	requested := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	loader := NewHTTPLoader(server.URL + "/project/src")
+	for _, name := range []string{"../../secret.h", "..", `..\secret.h`, "/etc/passwd", "inc/../../secret.h"} {
+		if _, err := loader.Load(name); !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("%s: expected fs.ErrInvalid, got %v", name, err)
+		}
+	}
+	if len(requested) != 0 {
+		t.Errorf("expected no requests, got %v", requested)
+	}
+
+	expectLoad(t, loader, "inc/../defs.h", "secret")
+	if len(requested) != 1 || requested[0] != "/project/src/defs.h" {
+		t.Errorf("expected a request for /project/src/defs.h, got %v", requested)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	overlay := NewOverlay(NewMapLoader(map[string]string{"a.c": "saved"}))
+	expectLoad(t, overlay, "a.c", "saved")
+
+	overlay.Set("a.c", "unsaved")
+	overlay.Set("new.c", "buffer")
+	expectLoad(t, overlay, "a.c", "unsaved")
+	expectLoad(t, overlay, "new.c", "buffer")
+
+	overlay.Remove("a.c")
+	overlay.Remove("new.c")
+	expectLoad(t, overlay, "a.c", "saved")
+	expectNotFound(t, overlay, "new.c")
+}
@@ -0,0 +1,30 @@
+package source
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// VirtualPath returns name in the form used for loader keys and
+// diagnostics: slash-separated and cleaned. Backslashes are treated as
+// separators on every platform, so names written on Windows resolve the
+// same way everywhere.
+func VirtualPath(name string) string {
+	return path.Clean(strings.ReplaceAll(name, `\`, "/"))
+}
+
+// DisplayPath returns name as it should appear in diagnostics. Files
+// under root are shown relative to it; other files keep their full path.
+// Either way the result uses forward slashes.
+func DisplayPath(root string, name string) string {
+	if !filepath.IsAbs(name) {
+		return VirtualPath(name)
+	}
+	if absRoot, err := filepath.Abs(root); err == nil {
+		if rel, err := filepath.Rel(absRoot, name); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return VirtualPath(filepath.ToSlash(rel))
+		}
+	}
+	return VirtualPath(filepath.ToSlash(name))
+}
@@ -0,0 +1,51 @@
+package source
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVirtualPath(t *testing.T) {
+	tests := map[string]string{
+		"main.c":            "main.c",
+		"./inc/../main.c":   "main.c",
+		`inc\defs.h`:        "inc/defs.h",
+		`C:\src\\inc\a.h`:   "C:/src/inc/a.h",
+		"/usr//include/x.h": "/usr/include/x.h",
+		"":                  ".",
+	}
+	for name, expected := range tests {
+		if result := VirtualPath(name); result != expected {
+			t.Errorf("%q: expected %q, got %q", name, expected, result)
+		}
+	}
+}
+
+func TestDisplayPath(t *testing.T) {
+	root := t.TempDir()
+	tests := map[string]string{
+		filepath.Join(root, "inc", "defs.h"): "inc/defs.h",
+		filepath.Join(root, "main.c"):        "main.c",
+		`inc\..\main.c`:                      "main.c",
+	}
+	outside := filepath.Join(filepath.Dir(root), "other.c")
+	tests[outside] = filepath.ToSlash(outside)
+
+	for name, expected := range tests {
+		if result := DisplayPath(root, name); result != expected {
+			t.Errorf("%q: expected %q, got %q", name, expected, result)
+		}
+	}
+}
+
+func TestLoadersUseVirtualPaths(t *testing.T) {
+	expectLoad(t, NewMapLoader(map[string]string{`inc\a.h`: "int a;"}), "./inc/a.h", "int a;")
+	expectLoad(t, NewFSLoader(fstest.MapFS{"inc/b.h": {Data: []byte("int b;")}}), `inc\b.h`, "int b;")
+
+	overlay := NewOverlay(NewMapLoader(nil))
+	overlay.Set(`src\main.c`, "int main;")
+	expectLoad(t, overlay, "src/main.c", "int main;")
+	overlay.Remove("./src/main.c")
+	expectNotFound(t, overlay, `src\main.c`)
+}
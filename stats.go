@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// usageStats is the local usage statistics file. It is only written
+// once the user has run "htc stats enable", and it never leaves the
+// machine.
+type usageStats struct {
+	Commands map[string]*commandStats `json:"commands"`
+}
+
+type commandStats struct {
+	Runs    int   `json:"runs"`
+	Failed  int   `json:"failed"`
+	TotalMS int64 `json:"total_ms"`
+}
+
+// statsPath returns the location of the statistics file. HTC_STATS_FILE
+// overrides the default under the user's config directory.
+func statsPath() (string, error) {
+	if path := os.Getenv("HTC_STATS_FILE"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "htc", "stats.json"), nil
+}
+
+// loadStats reads the statistics file at path. It returns fs.ErrNotExist
+// when statistics are not enabled.
+func loadStats(path string) (*usageStats, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	stats := &usageStats{}
+	if err := json.Unmarshal(data, stats); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if stats.Commands == nil {
+		stats.Commands = map[string]*commandStats{}
+	}
+	return stats, nil
+}
+
+// saveStats writes stats to path. The file is written under a temporary
+// name and renamed into place, so a concurrent htc run never reads a
+// partly written file; at worst one run's update is lost.
+func saveStats(path string, stats *usageStats) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".stats-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// recordUsage adds a run of the command at cmdpath to the statistics
+// file if statistics are enabled. Failures are ignored so that
+// statistics never get in the way of the command itself.
+func recordUsage(cmdpath string, code int, elapsed time.Duration) {
+	path, err := statsPath()
+	if err != nil {
+		return
+	}
+	stats, err := loadStats(path)
+	if err != nil {
+		return
+	}
+	entry := stats.Commands[cmdpath]
+	if entry == nil {
+		entry = &commandStats{}
+		stats.Commands[cmdpath] = entry
+	}
+	entry.Runs++
+	if code != 0 {
+		entry.Failed++
+	}
+	entry.TotalMS += elapsed.Milliseconds()
+	saveStats(path, stats)
+}
+
+// commandPath returns the names of the commands args select, or "" if
+// they do not lead to a runnable command.
+func commandPath(args []string) string {
+	cmd, path := root, []string{}
+	for len(cmd.subcommands) > 0 {
+		if len(args) == 0 || cmd.lookup(args[0]) == nil {
+			return ""
+		}
+		cmd = cmd.lookup(args[0])
+		path = append(path, cmd.name)
+		args = args[1:]
+	}
+	return strings.Join(path, " ")
+}
+
+func statsEnable(args []string) int {
+	return statsUpdate(args, func(path string) error {
+		_, err := loadStats(path)
+		var pathErr *fs.PathError
+		if err == nil {
+			return nil
+		} else if errors.As(err, &pathErr) && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			// a damaged file cannot be updated, so start over
+			fmt.Fprintf(os.Stderr, "stats: %s; starting a new statistics file\n", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		return saveStats(path, &usageStats{Commands: map[string]*commandStats{}})
+	})
+}
+
+func statsDisable(args []string) int {
+	return statsUpdate(args, func(path string) error {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	})
+}
+
+// statsUpdate runs change on the statistics file path for a command
+// that takes no arguments.
+func statsUpdate(args []string, change func(path string) error) int {
+	if len(args) != 0 {
+		usage(os.Stderr)
+		return 2
+	}
+	path, err := statsPath()
+	if err == nil {
+		err = change(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "stats: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+func statsShow(args []string) int {
+	if len(args) != 0 {
+		usage(os.Stderr)
+		return 2
+	}
+	path, err := statsPath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "stats: %s\n", err)
+		return 1
+	}
+	stats, err := loadStats(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintln(os.Stderr, "stats: usage statistics are not enabled; run 'htc stats enable'")
+		return 1
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "stats: %s\n", err)
+		return 1
+	}
+	writeStats(os.Stdout, stats)
+	return 0
+}
+
+// writeStats writes a table of the recorded commands, most used first.
+func writeStats(w io.Writer, stats *usageStats) {
+	names := make([]string, 0, len(stats.Commands))
+	for name := range stats.Commands {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := stats.Commands[names[i]], stats.Commands[names[j]]
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return names[i] < names[j]
+	})
+
+	fmt.Fprintf(w, "%-28s %6s %6s %10s\n", "command", "runs", "failed", "avg time")
+	for _, name := range names {
+		entry := stats.Commands[name]
+		avg := time.Duration(0)
+		if entry.Runs > 0 {
+			avg = time.Duration(entry.TotalMS/int64(entry.Runs)) * time.Millisecond
+		}
+		fmt.Fprintf(w, "%-28s %6d %6d %10s\n", name, entry.Runs, entry.Failed, avg)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandPath(t *testing.T) {
+	tests := map[string]string{
+		"examples show gcd": "examples show",
+		"stats enable":      "stats enable",
+		"examples":          "",
+		"nope":              "",
+		"":                  "",
+	}
+	for args, expected := range tests {
+		if result := commandPath(strings.Fields(args)); result != expected {
+			t.Errorf("%q: expected %q, got %q", args, expected, result)
+		}
+	}
+}
+
+func TestUsageStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "htc", "stats.json")
+	t.Setenv("HTC_STATS_FILE", path)
+
+	recordUsage("examples list", 0, time.Second)
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal("statistics recorded before being enabled")
+	}
+
+	if code := statsEnable(nil); code != 0 {
+		t.Fatalf("stats enable: exit code %d", code)
+	}
+	recordUsage("examples list", 0, 30*time.Millisecond)
+	recordUsage("examples show", 1, 10*time.Millisecond)
+	recordUsage("examples list", 0, 10*time.Millisecond)
+
+	stats, err := loadStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	writeStats(&sb, stats)
+	expected := "command                        runs failed   avg time\n" +
+		"examples list                     2      0       20ms\n" +
+		"examples show                     1      1       10ms\n"
+	if sb.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, sb.String())
+	}
+
+	if code := statsDisable(nil); code != 0 {
+		t.Fatalf("stats disable: exit code %d", code)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("statistics file left after disabling")
+	}
+}
+
+func TestStatsEnableRewritesDamagedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "stats.json")
+	t.Setenv("HTC_STATS_FILE", path)
+	if err := os.WriteFile(path, []byte(`{"commands": {"exam`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := statsEnable(nil); code != 0 {
+		t.Fatalf("stats enable: exit code %d", code)
+	}
+	recordUsage("examples list", 0, time.Second)
+	stats, err := loadStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry := stats.Commands["examples list"]; entry == nil || entry.Runs != 1 {
+		t.Errorf("expected one recorded run, got %+v", stats.Commands)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the statistics file, got %v", entries)
+	}
+}